@@ -0,0 +1,258 @@
+package main
+
+import(
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io/ioutil"
+  "strings"
+
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/iam"
+  "github.com/aws/aws-sdk-go/service/iam/iamiface"
+  "github.com/aws/aws-sdk-go/service/route53"
+  "github.com/aws/aws-sdk-go/service/sts"
+  "github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+/*
+ *  aws account - how to reach an account (shared config profile and/or a role to
+ *  assume) and, once reached, who it is
+ */
+type awsAccount struct {
+  alias string
+  externalId string
+  id string
+  mfaSerial string
+  profile string
+  roleArn string
+}
+
+/*
+ *  Reads accounts from a JSON file, eg.
+ *    {"accounts":[{"profile":"prod"},
+ *                 {"roleArn":"arn:aws:iam::123456789012:role/dns-audit","externalId":"...","mfaSerial":"...","alias":"marketing"}]}
+ *  A role without a profile is assumed using ambient credentials.
+ */
+func LoadAwsAccounts(path string) ([]*awsAccount, error) {
+  var accountsFile struct {
+    Accounts []struct {
+      Alias string `json:"alias"`
+      ExternalId string `json:"externalId"`
+      MfaSerial string `json:"mfaSerial"`
+      Profile string `json:"profile"`
+      RoleArn string `json:"roleArn"`
+    } `json:"accounts"`
+  }
+
+  content, err := ioutil.ReadFile(path)
+  if err != nil {
+    return nil, err
+  }
+
+  if err = json.Unmarshal(content, &accountsFile); err != nil {
+    return nil, fmt.Errorf("%s: %s", path, err.Error())
+  }
+
+  accounts := make([]*awsAccount, len(accountsFile.Accounts))
+  for i, entry := range accountsFile.Accounts {
+    if len(entry.Profile) == 0 && len(entry.RoleArn) == 0 {
+      return nil, fmt.Errorf("%s: account %d needs a profile, a roleArn or both", path, i + 1)
+    }
+
+    accounts[i] = &awsAccount{
+      alias: entry.Alias,
+      externalId: entry.ExternalId,
+      mfaSerial: entry.MfaSerial,
+      profile: entry.Profile,
+      roleArn: entry.RoleArn,
+    }
+  }
+
+  return accounts, nil
+}
+
+/*
+ *  Shorthand for accounts given on the command line: named profiles and role
+ *  arns (assumed with ambient credentials).
+ */
+func AwsAccountsFromLists(profiles []string, roleArns []string) []*awsAccount {
+  var accounts []*awsAccount
+
+  for _, profile := range profiles {
+    accounts = append(accounts, &awsAccount{profile: strings.TrimSpace(profile)})
+  }
+
+  for _, roleArn := range roleArns {
+    accounts = append(accounts, &awsAccount{roleArn: strings.TrimSpace(roleArn)})
+  }
+
+  return accounts
+}
+
+/*
+ *  Builds a session for the account. Profiles come from the shared config (which
+ *  may itself assume a role); an explicit role is assumed on top of that, with an
+ *  MFA code read from stdin when a serial is configured.
+ */
+func (a *awsAccount) NewSession() (*session.Session, error) {
+  sess, err := session.NewSessionWithOptions(session.Options{
+    AssumeRoleTokenProvider: stscreds.StdinTokenProvider,
+    Profile: a.profile,
+    SharedConfigState: session.SharedConfigEnable,
+  })
+  if err != nil || len(a.roleArn) == 0 {
+    return sess, err
+  }
+
+  roleCredentials := stscreds.NewCredentials(sess, a.roleArn, func(p *stscreds.AssumeRoleProvider) {
+    p.RoleSessionName = "domania"
+    if len(a.externalId) > 0 {
+      p.ExternalID = aws.String(a.externalId)
+    }
+
+    if len(a.mfaSerial) > 0 {
+      p.SerialNumber = aws.String(a.mfaSerial)
+      p.TokenProvider = stscreds.StdinTokenProvider
+    }
+  })
+
+  return sess.Copy(&aws.Config{Credentials: roleCredentials}), nil
+}
+
+/*
+ *  Fills in the account id and, unless one was configured, the account alias.
+ *  Listing aliases needs iam permissions the caller might not have, which isn't
+ *  worth failing over.
+ */
+func (a *awsAccount) Identify(stsSvc stsiface.STSAPI, iamSvc iamiface.IAMAPI) *awsRequest {
+  var identity *sts.GetCallerIdentityOutput
+  req := new(awsRequest)
+
+  //init request metadata
+  req.serviceName = "sts"
+  req.serviceFunction = "GetCallerIdentity"
+  //exec api call and handle error
+  identity, req.err = stsSvc.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+  if req.err != nil {
+    return req
+  }
+
+  a.id = aws.StringValue(identity.Account)
+  if len(a.alias) == 0 {
+    aliases, aliasErr := iamSvc.ListAccountAliases(&iam.ListAccountAliasesInput{})
+    if aliasErr == nil && len(aliases.AccountAliases) > 0 {
+      a.alias = aws.StringValue(aliases.AccountAliases[0])
+    }
+  }
+
+  return req
+}
+
+/*
+ *  Label used when reporting on the account.
+ */
+func (a *awsAccount) String() string {
+  if len(a.profile) > 0 {
+    return "profile " + a.profile
+  }
+
+  return "role " + a.roleArn
+}
+
+/*
+ *  multi-account provider - route53 zones from several accounts at once; each
+ *  zone is tagged with the account it came from
+ */
+type multiAccountProvider struct {
+  accounts []*route53Provider
+  zoneOwners map[string]*route53Provider
+}
+
+/*
+ *  Connects to (and identifies) every account up front so credential problems
+ *  show up before any output does.
+ */
+func NewMultiAccountProvider(accounts []*awsAccount, pageSize int64) (*multiAccountProvider, error) {
+  p := &multiAccountProvider{zoneOwners: make(map[string]*route53Provider)}
+
+  if len(accounts) == 0 {
+    return nil, errors.New("no aws accounts to scan")
+  }
+
+  for _, account := range accounts {
+    sess, err := account.NewSession()
+    if err != nil {
+      return nil, fmt.Errorf("%s: %s", account.String(), err.Error())
+    }
+
+    req := account.Identify(sts.New(sess), iam.New(sess))
+    if req.err != nil {
+      return nil, fmt.Errorf("%s: calling %s service function %s(): %s", account.String(), req.serviceName, req.serviceFunction, req.err.Error())
+    }
+
+    provider := NewRoute53Provider(route53.New(sess))
+    provider.account = account
+    provider.pageSize = pageSize
+    provider.sess = sess
+    p.accounts = append(p.accounts, provider)
+  }
+
+  return p, nil
+}
+func (p *multiAccountProvider) GetZones() ([]*zone, error) {
+  var zones []*zone
+
+  err := p.EachZone(func(z *zone) bool {
+    zones = append(zones, z)
+    return true
+  })
+
+  return zones, err
+}
+func (p *multiAccountProvider) EachZone(fn func(*zone) bool) error {
+  keepGoing := true
+
+  for _, provider := range p.accounts {
+    owner := provider
+    err := owner.EachZone(func(z *zone) bool {
+      p.zoneOwners[z.id] = owner
+      keepGoing = fn(z)
+      return keepGoing
+    })
+    if err != nil || !keepGoing {
+      return err
+    }
+  }
+
+  return nil
+}
+func (p *multiAccountProvider) GetRecordsets(zoneId string) (*recordset, error) {
+  //zone ids are only unique to an account; find out which one has it
+  if _, known := p.zoneOwners[zoneId]; !known {
+    if err := p.EachZone(func(z *zone) bool { return true }); err != nil {
+      return nil, err
+    }
+  }
+
+  owner, known := p.zoneOwners[zoneId]
+  if !known {
+    return nil, fmt.Errorf("hosted zone %s wasn't found in any account", zoneId)
+  }
+
+  return owner.GetRecordsets(zoneId)
+}
+func (p *multiAccountProvider) AttachHealthChecks(zoneId string, rset *recordset) {
+  //health checks belong to the same account as the zone
+  if owner, known := p.zoneOwners[zoneId]; known {
+    owner.AttachHealthChecks(zoneId, rset)
+  }
+}
+func (p *multiAccountProvider) Metadata() *providerMetadata {
+  return &providerMetadata{
+    description: fmt.Sprintf("AWS Route53 hosted zones across %d accounts", len(p.accounts)),
+    name: "route53",
+  }
+}
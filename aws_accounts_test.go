@@ -0,0 +1,106 @@
+package main
+import (
+  "io/ioutil"
+  "os"
+  "testing"
+
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/iam"
+  "github.com/aws/aws-sdk-go/service/iam/iamiface"
+  "github.com/aws/aws-sdk-go/service/route53"
+  "github.com/aws/aws-sdk-go/service/sts"
+  "github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+type mockSts struct {
+  stsiface.STSAPI
+  account string
+}
+func (m *mockSts) GetCallerIdentity(in *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+  return &sts.GetCallerIdentityOutput{Account: aws.String(m.account)}, nil
+}
+
+type mockIam struct {
+  iamiface.IAMAPI
+  aliases []string
+}
+func (m *mockIam) ListAccountAliases(in *iam.ListAccountAliasesInput) (*iam.ListAccountAliasesOutput, error) {
+  return &iam.ListAccountAliasesOutput{AccountAliases: aws.StringSlice(m.aliases)}, nil
+}
+
+func TestLoadAwsAccounts(t *testing.T) {
+  valid, _ := ioutil.TempFile("", "domania-accounts")
+  valid.WriteString(`{"accounts":[{"profile":"prod"},{"roleArn":"arn:aws:iam::123456789012:role/dns","externalId":"ext","mfaSerial":"arn:aws:iam::111111111111:mfa/me","alias":"marketing"}]}`)
+  valid.Close()
+  defer os.Remove(valid.Name())
+  invalid, _ := ioutil.TempFile("", "domania-accounts")
+  invalid.WriteString(`{"accounts":[{"alias":"nowhere"}]}`)
+  invalid.Close()
+  defer os.Remove(invalid.Name())
+
+  //test cases
+  //  1: profiles and roles (with external id, mfa and alias) are read
+  //  2: an account without a profile or role is rejected
+  accounts, err := LoadAwsAccounts(valid.Name())
+  if err != nil || len(accounts) != 2 || accounts[0].profile != "prod" || accounts[1].externalId != "ext" || accounts[1].mfaSerial == "" || accounts[1].alias != "marketing" {
+    t.Errorf("tc1 - unexpected accounts: %+v (error: %v)", accounts, err)
+  }
+
+  if _, err = LoadAwsAccounts(invalid.Name()); err == nil {
+    t.Error("tc2 - expected an error for an account without a profile or role")
+  }
+}
+
+func TestAwsAccountIdentify(t *testing.T) {
+  unnamed := &awsAccount{profile: "prod"}
+  named := &awsAccount{profile: "dev", alias: "configured"}
+
+  //test cases
+  //  1: account id comes from sts, alias from iam
+  //  2: a configured alias is kept
+  unnamed.Identify(&mockSts{account: "111111111111"}, &mockIam{aliases: []string{"prod-alias"}})
+  if unnamed.id != "111111111111" || unnamed.alias != "prod-alias" {
+    t.Errorf("tc1 - unexpected identity: %+v", unnamed)
+  }
+
+  named.Identify(&mockSts{account: "222222222222"}, &mockIam{aliases: []string{"dev-alias"}})
+  if named.id != "222222222222" || named.alias != "configured" {
+    t.Errorf("tc2 - unexpected identity: %+v", named)
+  }
+}
+
+func TestMultiAccountProvider(t *testing.T) {
+  createAccount := func(id string, zoneId string, zoneName string, recordName string) *route53Provider {
+    provider := NewRoute53Provider(&mockRoute53{
+      hostedZones: []*route53.HostedZone{
+        &route53.HostedZone{Id: aws.String("/hostedzone/" + zoneId), Name: aws.String(zoneName), ResourceRecordSetCount: aws.Int64(1)},
+      },
+      recordPages: [][]*route53.ResourceRecordSet{
+        []*route53.ResourceRecordSet{createRoute53Recordset(recordName, "A", "192.0.2.1")},
+      },
+    })
+    provider.account = &awsAccount{id: id, alias: "alias-" + id}
+    return provider
+  }
+  provider := &multiAccountProvider{
+    accounts: []*route53Provider{
+      createAccount("111111111111", "ZPROD", "example.com.", "www.example.com."),
+      createAccount("222222222222", "ZMKTG", "example.net.", "www.example.net."),
+    },
+    zoneOwners: make(map[string]*route53Provider),
+  }
+
+  //test cases
+  //  1: zones from every account are listed and tagged with their account
+  //  2: records are fetched from the account owning the zone (even without a prior listing)
+  zones, err := provider.GetZones()
+  if err != nil || len(zones) != 2 || zones[1].accountId != "222222222222" || zones[1].accountAlias != "alias-222222222222" {
+    t.Errorf("tc1 - unexpected zones: %+v (error: %v)", zones, err)
+  }
+
+  provider.zoneOwners = make(map[string]*route53Provider)
+  zoneRecords, err := provider.GetRecordsets("ZMKTG")
+  if err != nil || (*zoneRecords)["A"][0].name != "www.example.net" {
+    t.Errorf("tc2 - expected records from the second account: %+v (error: %v)", zoneRecords, err)
+  }
+}
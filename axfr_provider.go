@@ -0,0 +1,362 @@
+package main
+
+import(
+  "errors"
+  "fmt"
+  "net"
+  "os"
+  "path/filepath"
+  "strings"
+  "time"
+
+  "github.com/miekg/dns"
+)
+
+/*
+ *  axfr provider - zones pulled straight from an authoritative nameserver by
+ *  zone transfer; when a cache directory is given, repeat runs use IXFR to fetch
+ *  only what changed since the cached serial
+ */
+type axfrProvider struct {
+  cacheDir string
+  server string
+  transferred map[string][]dns.RR
+  tsig *tsigKey
+  zoneNames []string
+}
+
+/*
+ *  TSIG key used to authenticate transfers
+ */
+type tsigKey struct {
+  algorithm string
+  name string
+  secret string
+}
+
+/*
+ *  Creates a zone transfer provider for the given zones on a single server.
+ *  The server may omit a port (53 is assumed).
+ */
+func NewAxfrProvider(server string, zoneNames []string, tsig *tsigKey, cacheDir string) (*axfrProvider, error) {
+  if len(server) == 0 || len(zoneNames) == 0 {
+    return nil, errors.New("axfr provider requires a nameserver and at least one zone")
+  }
+
+  if _, _, err := net.SplitHostPort(server); err != nil {
+    server = net.JoinHostPort(server, "53")
+  }
+
+  return &axfrProvider{
+    cacheDir: cacheDir,
+    server: server,
+    transferred: make(map[string][]dns.RR),
+    tsig: tsig,
+    zoneNames: zoneNames,
+  }, nil
+}
+
+/*
+ *  Reads a TSIG key in the same form dig accepts (-y [algorithm:]name:secret);
+ *  hmac-sha256 is used when no algorithm is given.
+ */
+func ParseTsigKey(spec string) (*tsigKey, error) {
+  parts := strings.Split(spec, ":")
+  key := &tsigKey{algorithm: dns.HmacSHA256}
+
+  switch len(parts) {
+  case 2:
+    key.name, key.secret = parts[0], parts[1]
+  case 3:
+    key.algorithm, key.name, key.secret = dns.Fqdn(strings.ToLower(parts[0])), parts[1], parts[2]
+  default:
+    return nil, fmt.Errorf("tsig key should look like [algorithm:]name:secret")
+  }
+
+  key.name = dns.Fqdn(strings.ToLower(key.name))
+  return key, nil
+}
+func (p *axfrProvider) GetZones() ([]*zone, error) {
+  zones := make([]*zone, len(p.zoneNames))
+
+  for i, zoneName := range p.zoneNames {
+    zoneRecordset, err := p.GetRecordsets(zoneName)
+    if err != nil {
+      return nil, err
+    }
+
+    z := new(zone)
+    z.id = strings.TrimSuffix(zoneName, ".")
+    z.SetName(zoneName)
+    recordCount := int64(0)
+    for _, records := range *zoneRecordset {
+      recordCount += int64(len(records))
+    }
+    z.recordCount = &recordCount
+    zones[i] = z
+  }
+
+  return zones, nil
+}
+func (p *axfrProvider) GetRecordsets(zoneId string) (*recordset, error) {
+  fqdn := dns.Fqdn(strings.ToLower(zoneId))
+  zoneRecordset := make(recordset)
+
+  rrs, found := p.transferred[fqdn]
+  if !found {
+    var err error
+    if rrs, err = p.Transfer(fqdn); err != nil {
+      return nil, err
+    }
+    p.transferred[fqdn] = rrs
+  }
+
+  zoneRecordset.HashResourceRecords(rrs)
+
+  return &zoneRecordset, nil
+}
+func (p *axfrProvider) Metadata() *providerMetadata {
+  return &providerMetadata{
+    description: "zone transfer (AXFR/IXFR) from " + p.server,
+    name: "axfr",
+  }
+}
+
+/*
+ *  Fetches a zone's records. Without a cached copy (or if the server refuses an
+ *  incremental transfer) the whole zone is transferred; otherwise the cached
+ *  copy is brought up to date with IXFR. The cache is rewritten either way.
+ */
+func (p *axfrProvider) Transfer(fqdn string) ([]dns.RR, error) {
+  var rrs []dns.RR
+  var err error
+
+  cached := p.readCache(fqdn)
+  if cachedSoa := findSoa(cached); cachedSoa != nil {
+    rrs, err = p.incrementalTransfer(fqdn, cachedSoa, cached)
+  }
+
+  if rrs == nil {
+    if rrs, err = p.fullTransfer(fqdn); err != nil {
+      return nil, err
+    }
+  }
+
+  if err = p.writeCache(fqdn, rrs); err != nil {
+    fmt.Fprintf(os.Stderr, "[Warning] unable to cache zone %s...\n%s\n\n", fqdn, err.Error())
+  }
+
+  return rrs, nil
+}
+func (p *axfrProvider) fullTransfer(fqdn string) ([]dns.RR, error) {
+  m := new(dns.Msg)
+  m.SetAxfr(fqdn)
+
+  rrs, err := p.exchange(m)
+  if err != nil {
+    return nil, err
+  }
+
+  //the closing SOA just marks the end of the transfer
+  if len(rrs) > 1 {
+    rrs = rrs[:len(rrs) - 1]
+  }
+
+  return rrs, nil
+}
+
+/*
+ *  IXFR responses are one of: a lone SOA (nothing changed if it still has the
+ *  cached serial), a full zone (the server fell back to AXFR) or a sequence of
+ *  deletions and additions bracketed by old and new SOAs (RFC 1995). A nil
+ *  result without an error means "do a full transfer instead".
+ */
+func (p *axfrProvider) incrementalTransfer(fqdn string, cachedSoa *dns.SOA, cached []dns.RR) ([]dns.RR, error) {
+  m := new(dns.Msg)
+  m.SetIxfr(fqdn, cachedSoa.Serial, cachedSoa.Ns, cachedSoa.Mbox)
+
+  rrs, err := p.exchange(m)
+  if err != nil || len(rrs) == 0 {
+    return nil, nil
+  }
+
+  if len(rrs) == 1 {
+    //a newer serial without the differences (the server couldn't send them)
+    if soa, isSoa := rrs[0].(*dns.SOA); !isSoa || soa.Serial != cachedSoa.Serial {
+      return nil, nil
+    }
+    return cached, nil
+  }
+
+  if _, isSoa := rrs[1].(*dns.SOA); !isSoa {
+    return rrs[:len(rrs) - 1], nil
+  }
+
+  //apply each difference sequence to the cached copy
+  current := make(map[string]dns.RR)
+  order := make([]string, 0, len(cached))
+  for _, rr := range cached {
+    key := rrKey(rr)
+    current[key] = rr
+    order = append(order, key)
+  }
+
+  deleting := false
+  for _, rr := range rrs[1:len(rrs) - 1] {
+    if _, isSoa := rr.(*dns.SOA); isSoa {
+      deleting = !deleting
+    }
+
+    key := rrKey(rr)
+    if deleting {
+      delete(current, key)
+    } else {
+      if _, exists := current[key]; !exists {
+        order = append(order, key)
+      }
+      current[key] = rr
+    }
+  }
+
+  updated := make([]dns.RR, 0, len(current))
+  for _, key := range order {
+    if rr, exists := current[key]; exists {
+      updated = append(updated, rr)
+      //keep order keys unique in case a record is removed and added back
+      delete(current, key)
+    }
+  }
+
+  //SOA first, as it would be in a full transfer
+  for i, rr := range updated {
+    if _, isSoa := rr.(*dns.SOA); isSoa {
+      updated[0], updated[i] = updated[i], updated[0]
+      break
+    }
+  }
+
+  return updated, nil
+}
+
+/*
+ *  Runs a transfer request and gathers every record from every envelope.
+ */
+func (p *axfrProvider) exchange(m *dns.Msg) ([]dns.RR, error) {
+  var rrs []dns.RR
+  tr := &dns.Transfer{DialTimeout: 10 * time.Second, ReadTimeout: 30 * time.Second}
+
+  if p.tsig != nil {
+    tr.TsigSecret = map[string]string{p.tsig.name: p.tsig.secret}
+    m.SetTsig(p.tsig.name, p.tsig.algorithm, 300, time.Now().Unix())
+  }
+
+  envelopes, err := tr.In(m, p.server)
+  if err != nil {
+    return nil, err
+  }
+
+  for envelope := range envelopes {
+    if envelope.Error != nil {
+      return nil, fmt.Errorf("transfer of %s failed: %s", m.Question[0].Name, envelope.Error.Error())
+    }
+    rrs = append(rrs, envelope.RR...)
+  }
+
+  return rrs, nil
+}
+func (p *axfrProvider) cachePath(fqdn string) string {
+  return filepath.Join(p.cacheDir, strings.TrimSuffix(fqdn, ".") + ".zone")
+}
+func (p *axfrProvider) readCache(fqdn string) []dns.RR {
+  var rrs []dns.RR
+
+  if len(p.cacheDir) == 0 {
+    return nil
+  }
+
+  cacheFile, err := os.Open(p.cachePath(fqdn))
+  if err != nil {
+    return nil
+  }
+
+  defer cacheFile.Close()
+  zp := dns.NewZoneParser(cacheFile, fqdn, cacheFile.Name())
+  for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+    rrs = append(rrs, rr)
+  }
+
+  //a damaged cache is just a cache miss
+  if zp.Err() != nil {
+    return nil
+  }
+
+  return rrs
+}
+func (p *axfrProvider) writeCache(fqdn string, rrs []dns.RR) error {
+  if len(p.cacheDir) == 0 {
+    return nil
+  }
+
+  if err := os.MkdirAll(p.cacheDir, 0755); err != nil {
+    return err
+  }
+
+  cacheFile, err := os.Create(p.cachePath(fqdn))
+  if err != nil {
+    return err
+  }
+
+  defer cacheFile.Close()
+  for _, rr := range rrs {
+    if _, err = fmt.Fprintln(cacheFile, rr.String()); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+/*
+ *  identity of a record for IXFR bookkeeping; the ttl isn't part of it
+ */
+func rrKey(rr dns.RR) string {
+  keyed := dns.Copy(rr)
+  keyed.Header().Ttl = 0
+
+  return strings.ToLower(keyed.String())
+}
+func findSoa(rrs []dns.RR) *dns.SOA {
+  for _, rr := range rrs {
+    if soa, isSoa := rr.(*dns.SOA); isSoa {
+      return soa
+    }
+  }
+
+  return nil
+}
+
+/*
+ *  hash transferred resource records into a map of dns record types, grouping
+ *  values by name the same way route53 recordsets are
+ */
+func (rset *recordset) HashResourceRecords(rrs []dns.RR) {
+  grouped := make(map[string]*record)
+
+  for _, rr := range rrs {
+    header := rr.Header()
+    rrType := dns.TypeToString[header.Rrtype]
+    key := rrType + " " + strings.ToLower(header.Name)
+    currentRecordset, found := grouped[key]
+    if !found {
+      currentRecordset = new(record)
+      currentRecordset.rrType = rrType
+      currentRecordset.name = strings.TrimSuffix(header.Name, ".")
+      currentRecordset.ttl = int64(header.Ttl)
+      grouped[key] = currentRecordset
+      (*rset)[rrType] = append((*rset)[rrType], currentRecordset)
+    }
+
+    //the value is everything after the header (name, ttl, class and type)
+    currentRecordset.values = append(currentRecordset.values, strings.TrimPrefix(rr.String(), header.String()))
+  }
+}
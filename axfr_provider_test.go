@@ -0,0 +1,198 @@
+package main
+import (
+  "io/ioutil"
+  "net"
+  "os"
+  "sync"
+  "testing"
+
+  "github.com/miekg/dns"
+)
+
+const testTsigName string = "transfer-key."
+const testTsigSecret string = "c2VjcmV0LWtleS1mb3ItdGVzdGluZw=="
+
+/*
+ *  in-process authoritative server for example.com.; serial 2 is current, and
+ *  an IXFR from serial 1 removes one A record and adds another (from any other
+ *  serial, only the current SOA is sent back)
+ */
+func createTransferServer(t *testing.T, requireTsig bool) (string, func()) {
+  rr := func(s string) dns.RR {
+    parsed, err := dns.NewRR(s)
+    if err != nil {
+      t.Fatal(err)
+    }
+    return parsed
+  }
+  soa1 := rr("example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 300")
+  soa2 := rr("example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 2 7200 3600 1209600 300")
+  zone := []dns.RR{
+    soa2,
+    rr("example.com. 3600 IN NS ns1.example.com."),
+    rr("www.example.com. 300 IN A 192.0.2.1"),
+    rr("www.example.com. 300 IN A 192.0.2.3"),
+    rr("example.com. 300 IN TXT \"v=spf1 -all\""),
+  }
+
+  handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+    if requireTsig && (r.IsTsig() == nil || w.TsigStatus() != nil) {
+      m := new(dns.Msg)
+      m.SetRcode(r, dns.RcodeRefused)
+      w.WriteMsg(m)
+      return
+    }
+
+    var envelope []dns.RR
+    switch r.Question[0].Qtype {
+    case dns.TypeAXFR:
+      envelope = append(append([]dns.RR{}, zone...), soa2)
+    case dns.TypeIXFR:
+      envelope = []dns.RR{soa2}
+      if len(r.Ns) > 0 && r.Ns[0].(*dns.SOA).Serial == 1 {
+        envelope = []dns.RR{soa2, soa1, rr("www.example.com. 300 IN A 192.0.2.2"), soa2, rr("www.example.com. 300 IN A 192.0.2.3"), soa2}
+      }
+    }
+
+    ch := make(chan *dns.Envelope)
+    tr := new(dns.Transfer)
+    var wg sync.WaitGroup
+    wg.Add(1)
+    go func() {
+      tr.Out(w, r, ch)
+      wg.Done()
+    }()
+    ch <- &dns.Envelope{RR: envelope}
+    close(ch)
+    wg.Wait()
+  })
+
+  listener, err := net.Listen("tcp", "127.0.0.1:0")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  server := &dns.Server{Listener: listener, Handler: handler}
+  if requireTsig {
+    server.TsigSecret = map[string]string{testTsigName: testTsigSecret}
+  }
+
+  started := make(chan bool)
+  server.NotifyStartedFunc = func() { close(started) }
+  go server.ActivateAndServe()
+  <-started
+
+  return listener.Addr().String(), func() { server.Shutdown() }
+}
+
+func TestAxfrProviderTransfer(t *testing.T) {
+  address, shutdown := createTransferServer(t, false)
+  defer shutdown()
+
+  //test cases
+  //  1: a full transfer fills the recordset like route53 recordsets (grouped by name and type)
+  //  2: the closing SOA isn't counted as a record
+  provider, _ := NewAxfrProvider(address, []string{"example.com"}, nil, "")
+  zones, err := provider.GetZones()
+  if err != nil {
+    t.Fatalf("unexpected error: %s", err.Error())
+  }
+
+  zoneRecords, _ := provider.GetRecordsets("example.com")
+  if len((*zoneRecords)["A"]) != 1 || len((*zoneRecords)["A"][0].values) != 2 || (*zoneRecords)["A"][0].name != "www.example.com" {
+    t.Errorf("tc1 - unexpected A records: %+v", (*zoneRecords)["A"])
+  }
+
+  if len((*zoneRecords)["SOA"]) != 1 || zones[0].recordCount == nil || *zones[0].recordCount != 4 {
+    t.Errorf("tc2 - expected one SOA and 4 recordsets, found %d SOA and %v recordsets", len((*zoneRecords)["SOA"]), zones[0].recordCount)
+  }
+}
+
+func TestAxfrProviderIncrementalTransfer(t *testing.T) {
+  address, shutdown := createTransferServer(t, false)
+  defer shutdown()
+  cacheDir, _ := ioutil.TempDir("", "domania-ixfr")
+  defer os.RemoveAll(cacheDir)
+
+  //seed the cache with serial 1 of the zone
+  cached := "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 300\n" +
+            "example.com. 3600 IN NS ns1.example.com.\n" +
+            "www.example.com. 300 IN A 192.0.2.1\n" +
+            "www.example.com. 300 IN A 192.0.2.2\n"
+  ioutil.WriteFile(cacheDir + "/example.com.zone", []byte(cached), 0644)
+
+  //test cases
+  //  1: deletions and additions are applied to the cached copy
+  //  2: the SOA moves to the new serial
+  //  3: the cache is rewritten with the new serial
+  provider, _ := NewAxfrProvider(address, []string{"example.com"}, nil, cacheDir)
+  zoneRecords, err := provider.GetRecordsets("example.com")
+  if err != nil {
+    t.Fatalf("unexpected error: %s", err.Error())
+  }
+
+  aValues := (*zoneRecords)["A"][0].values
+  if len(aValues) != 2 || aValues[0] != "192.0.2.1" || aValues[1] != "192.0.2.3" {
+    t.Errorf("tc1 - expected 192.0.2.1 and 192.0.2.3, received: %+v", aValues)
+  }
+
+  if soa := (*zoneRecords)["SOA"]; len(soa) != 1 || soa[0].values[0] != "ns1.example.com. hostmaster.example.com. 2 7200 3600 1209600 300" {
+    t.Errorf("tc2 - unexpected SOA: %+v", soa)
+  }
+
+  rewritten, _ := NewAxfrProvider(address, []string{"example.com"}, nil, cacheDir)
+  if cachedSoa := findSoa(rewritten.readCache("example.com.")); cachedSoa == nil || cachedSoa.Serial != 2 {
+    t.Errorf("tc3 - expected cache to hold serial 2: %+v", cachedSoa)
+  }
+}
+
+func TestAxfrProviderIncrementalTransferSoaOnly(t *testing.T) {
+  address, shutdown := createTransferServer(t, false)
+  defer shutdown()
+  cacheDir, _ := ioutil.TempDir("", "domania-ixfr")
+  defer os.RemoveAll(cacheDir)
+  seed := func(serial string) {
+    cached := "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. " + serial + " 7200 3600 1209600 300\n" +
+              "www.example.com. 300 IN A 198.51.100.1\n"
+    ioutil.WriteFile(cacheDir + "/example.com.zone", []byte(cached), 0644)
+  }
+
+  //test cases
+  //  1: a lone SOA with the cached serial keeps the cached copy
+  //  2: a lone SOA with another serial falls back to a full transfer
+  seed("2")
+  unchanged, _ := NewAxfrProvider(address, []string{"example.com"}, nil, cacheDir)
+  if zoneRecords, err := unchanged.GetRecordsets("example.com"); err != nil || (*zoneRecords)["A"][0].values[0] != "198.51.100.1" {
+    t.Errorf("tc1 - expected the cached zone: %+v (error: %v)", zoneRecords, err)
+  }
+
+  seed("3")
+  changed, _ := NewAxfrProvider(address, []string{"example.com"}, nil, cacheDir)
+  zoneRecords, err := changed.GetRecordsets("example.com")
+  if err != nil || len((*zoneRecords)["A"][0].values) != 2 || (*zoneRecords)["A"][0].values[0] != "192.0.2.1" {
+    t.Errorf("tc2 - expected a full transfer: %+v (error: %v)", zoneRecords, err)
+  }
+}
+
+func TestAxfrProviderTsig(t *testing.T) {
+  address, shutdown := createTransferServer(t, true)
+  defer shutdown()
+
+  //test cases
+  //  1: an unsigned transfer is refused
+  //  2: a transfer signed with the right key succeeds
+  unsigned, _ := NewAxfrProvider(address, []string{"example.com"}, nil, "")
+  if _, err := unsigned.GetRecordsets("example.com"); err == nil {
+    t.Error("tc1 - expected unsigned transfer to fail")
+  }
+
+  key, err := ParseTsigKey("hmac-sha256:transfer-key:" + testTsigSecret)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  signed, _ := NewAxfrProvider(address, []string{"example.com"}, key, "")
+  if zoneRecords, err := signed.GetRecordsets("example.com"); err != nil || len((*zoneRecords)["A"]) != 1 {
+    t.Errorf("tc2 - expected signed transfer to succeed: %v", err)
+  }
+}
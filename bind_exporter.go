@@ -0,0 +1,126 @@
+package main
+
+import(
+  "fmt"
+  "io"
+  "strconv"
+  "strings"
+)
+
+//ttl written as $TTL when the zone has no SOA to take one from
+const defaultExportTTL int64 = 300
+
+/*
+ *  bind exporter - RFC 1035 master file. Route53 constructs with no zone file
+ *  equivalent are kept as comments: alias records are commented out entirely,
+ *  records with a routing policy are written with a comment describing it (a
+ *  plain zone file answers with all of them).
+ */
+type bindExporter struct {}
+func (e *bindExporter) Extension() string {
+  return "zone"
+}
+func (e *bindExporter) Export(w io.Writer, z *zone, zoneRecords *recordset) error {
+  var out strings.Builder
+  origin := strings.TrimSuffix(z.DomainToString(), ".")
+  records := SortedRecords(zoneRecords, origin)
+  defaultTtl := defaultExportTTL
+
+  if soa := (*zoneRecords)["SOA"]; len(soa) > 0 && soa[0].ttl > 0 {
+    defaultTtl = soa[0].ttl
+  }
+
+  fmt.Fprintf(&out, "; %s (zone %s)\n", origin, z.id)
+  fmt.Fprintf(&out, "$ORIGIN %s.\n", origin)
+  fmt.Fprintf(&out, "$TTL %d\n", defaultTtl)
+  for _, rec := range records {
+    owner := RelativeOwnerName(rec.name, origin)
+
+    if rec.isAlias {
+      //there's no zone file form of an alias; keep a note of where it pointed
+      fmt.Fprintf(&out, "; alias: %s %s -> %s (hosted zone %s, evaluate target health %t)", owner, rec.rrType, strings.Join(rec.values, " "), rec.zoneRef, rec.evaluateTargetHealth)
+      if routing := rec.RoutingToString(); len(routing) > 0 {
+        fmt.Fprintf(&out, " [%s]", routing)
+      }
+      out.WriteString("\n")
+      continue
+    }
+
+    if routing := rec.RoutingToString(); len(routing) > 0 {
+      fmt.Fprintf(&out, "; %s\n", routing)
+    }
+    for _, value := range rec.values {
+      fmt.Fprintf(&out, "%s\t%d\tIN\t%s\t%s\n", owner, rec.ttl, rec.rrType, ZoneFileValue(rec.rrType, value))
+    }
+  }
+
+  _, err := io.WriteString(w, out.String())
+  return err
+}
+
+/*
+ *  Owner name as written in a zone file: "@" for the apex, relative for names in
+ *  the zone and absolute (trailing dot) for anything else.
+ */
+func RelativeOwnerName(name string, origin string) string {
+  name = strings.TrimSuffix(name, ".")
+
+  switch {
+  case strings.EqualFold(name, origin):
+    return "@"
+  case strings.HasSuffix(strings.ToLower(name), "." + strings.ToLower(origin)):
+    return name[:len(name) - len(origin) - 1]
+  }
+
+  return name + "."
+}
+
+/*
+ *  A record value in zone file form. Character-strings (TXT, SPF and the CAA
+ *  value) are re-quoted so quotes, backslashes and unprintable bytes are
+ *  escaped the RFC 1035 way, and TXT strings are split at 255 bytes.
+ */
+func ZoneFileValue(rrType string, value string) string {
+  parsed := ParseRecordValue(rrType, value)
+
+  switch {
+  case parsed.characterStrings != nil:
+    var quoted []string
+    for _, characterString := range parsed.characterStrings {
+      for len(characterString) > 255 {
+        quoted = append(quoted, ZoneFileCharacterString(characterString[:255]))
+        characterString = characterString[255:]
+      }
+      quoted = append(quoted, ZoneFileCharacterString(characterString))
+    }
+    return strings.Join(quoted, " ")
+  case parsed.caa != nil:
+    return strconv.Itoa(parsed.caa.flag) + " " + parsed.caa.tag + " " + ZoneFileCharacterString(parsed.caa.value)
+  }
+
+  return AbsoluteRecordValue(rrType, value)
+}
+
+/*
+ *  quoted character-string with \DDD escapes for anything unprintable
+ */
+func ZoneFileCharacterString(text string) string {
+  var quoted strings.Builder
+
+  quoted.WriteString("\"")
+  for i := 0; i < len(text); i++ {
+    c := text[i]
+    switch {
+    case c == '"' || c == '\\':
+      quoted.WriteByte('\\')
+      quoted.WriteByte(c)
+    case c < ' ' || c > '~':
+      fmt.Fprintf(&quoted, "\\%03d", c)
+    default:
+      quoted.WriteByte(c)
+    }
+  }
+  quoted.WriteString("\"")
+
+  return quoted.String()
+}
@@ -0,0 +1,106 @@
+package main
+import (
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+/*
+ *  helper that builds a zone with records covering everything the bind exporter
+ *  treats specially
+ */
+func createExportZone() (*zone, *recordset) {
+  weight := int64(10)
+  z := &zone{id: "Z1EXAMPLE"}
+  z.SetName("example.com.")
+  zoneRecords := recordset{
+    "SOA": []*record{&record{name: "example.com", ttl: 900, values: []string{"ns-1.awsdns-01.org. hostmaster.example.com. 1 7200 900 1209600 86400"}}},
+    "NS": []*record{
+      &record{name: "example.com", ttl: 172800, values: []string{"ns-1.awsdns-01.org.", "ns-2.awsdns-02.net"}},
+      &record{name: "dev.example.com", ttl: 300, values: []string{"ns1.dev.example.net."}},
+    },
+    "A": []*record{
+      &record{name: "example.com", isAlias: true, zoneRef: "Z35SXDOTRQ7X7K", evaluateTargetHealth: true, values: []string{"dualstack.lb.amazonaws.com."}},
+      &record{name: "www.example.com", ttl: 60, setIdentifier: "blue", weight: &weight, values: []string{"192.0.2.1"}},
+      &record{name: "*.example.com", ttl: 300, values: []string{"192.0.2.9"}},
+    },
+    "MX": []*record{&record{name: "example.com", ttl: 300, values: []string{"10 mail.example.com"}}},
+    "TXT": []*record{&record{name: "example.com", ttl: 300, values: []string{"\"v=spf1 a mx; ~all\" \"say \\\"hi\\\", \\\\ \\009tab\"", "\"" + strings.Repeat("k", 300) + "\""}}},
+    "CAA": []*record{&record{name: "example.com", ttl: 300, values: []string{"0 issue \"letsencrypt.org; validationmethods=dns-01\""}}},
+  }
+
+  return z, &zoneRecords
+}
+
+func TestBindExporterExport(t *testing.T) {
+  var output strings.Builder
+  z, zoneRecords := createExportZone()
+
+  //test cases
+  //  1: $ORIGIN and $TTL (from the SOA) lead the file, SOA then apex NS first
+  //  2: alias records are comments, routing policies are described in a comment
+  //  3: relative owners, absolute names in rdata, long TXT strings split at 255
+  err := new(bindExporter).Export(&output, z, zoneRecords)
+  lines := strings.Split(output.String(), "\n")
+  if err != nil || lines[1] != "$ORIGIN example.com." || lines[2] != "$TTL 900" || !strings.HasPrefix(lines[3], "@\t900\tIN\tSOA\t") || !strings.HasPrefix(lines[4], "@\t172800\tIN\tNS\t") {
+    t.Errorf("tc1 - unexpected file start: %s (error: %v)", output.String(), err)
+  }
+
+  if !strings.Contains(output.String(), "; alias: @ A -> dualstack.lb.amazonaws.com. (hosted zone Z35SXDOTRQ7X7K, evaluate target health true)\n") || !strings.Contains(output.String(), "; weighted: blue, weight 10\n") {
+    t.Errorf("tc2 - expected alias and routing comments: %s", output.String())
+  }
+
+  if !strings.Contains(output.String(), "@\t172800\tIN\tNS\tns-2.awsdns-02.net.\n") || !strings.Contains(output.String(), "@\t300\tIN\tMX\t10 mail.example.com.\n") || !strings.Contains(output.String(), "\"" + strings.Repeat("k", 255) + "\" \"" + strings.Repeat("k", 45) + "\"") || !strings.Contains(output.String(), "\n*\t300\tIN\tA\t192.0.2.9\n") {
+    t.Errorf("tc3 - unexpected records: %s", output.String())
+  }
+}
+
+func TestBindExporterRoundTrip(t *testing.T) {
+  var output strings.Builder
+  z, zoneRecords := createExportZone()
+  new(bindExporter).Export(&output, z, zoneRecords)
+  dir := createZoneFiles(t, map[string]string{"example.com.zone": output.String()})
+  defer os.RemoveAll(dir)
+
+  //test cases
+  //  1: the exported file parses
+  //  2: every record that isn't an alias comes back with the same ttl and values
+  //  3: TXT and CAA data survive quoting
+  zf, err := ParseZoneFile(filepath.Join(dir, "example.com.zone"), "")
+  if err != nil || zf.origin != "example.com." {
+    t.Fatalf("tc1 - exported file didn't parse: %v\n%s", err, output.String())
+  }
+
+  parsed := make(recordset)
+  parsed.HashZoneFileRecords(zf.records)
+  if len(parsed["A"]) != 2 || len(parsed["NS"]) != 2 || parsed["A"][0].name != "*.example.com" || parsed["A"][1].ttl != 60 || parsed["SOA"][0].ttl != 900 {
+    t.Errorf("tc2 - unexpected records: %+v", parsed)
+  }
+
+  original := ParseRecordValue("TXT", (*zoneRecords)["TXT"][0].values[0])
+  roundTripped := ParseRecordValue("TXT", parsed["TXT"][0].values[0])
+  long := ParseRecordValue("TXT", parsed["TXT"][0].values[1])
+  caa := ParseRecordValue("CAA", parsed["CAA"][0].values[0])
+  if strings.Join(original.characterStrings, "|") != strings.Join(roundTripped.characterStrings, "|") || long.Text() != strings.Repeat("k", 300) || caa.caa == nil || caa.caa.value != "letsencrypt.org; validationmethods=dns-01" {
+    t.Errorf("tc3 - character-strings changed: %q, %q, %+v", roundTripped.characterStrings, long.characterStrings, caa.caa)
+  }
+}
+
+func TestRelativeOwnerName(t *testing.T) {
+  //test cases
+  //  1: the apex is @
+  //  2: names in the zone are relative, whatever their case
+  //  3: names outside the zone stay absolute
+  if tc1 := RelativeOwnerName("Example.com.", "example.com"); tc1 != "@" {
+    t.Errorf("tc1 - expected @, received: %s", tc1)
+  }
+
+  if tc2 := RelativeOwnerName("WWW.Example.COM", "example.com"); tc2 != "WWW" {
+    t.Errorf("tc2 - expected WWW, received: %s", tc2)
+  }
+
+  if tc3 := RelativeOwnerName("notexample.com", "example.com"); tc3 != "notexample.com." {
+    t.Errorf("tc3 - expected notexample.com., received: %s", tc3)
+  }
+}
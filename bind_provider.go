@@ -0,0 +1,80 @@
+package main
+
+import(
+  "errors"
+  "fmt"
+  "strings"
+)
+
+/*
+ *  bind provider - zones read from RFC 1035 master (zone) files; no cloud
+ *  credentials required
+ */
+type bindProvider struct {
+  recordsets map[string]*recordset
+  zones []*zone
+}
+
+/*
+ *  Reads every zone file up front so parse errors surface before any output.
+ *  Each file spec is a path, optionally prefixed by the zone's origin when the
+ *  file doesn't set one itself (eg. example.com=db.example).
+ */
+func NewBindProvider(fileSpecs []string) (*bindProvider, error) {
+  p := &bindProvider{recordsets: make(map[string]*recordset)}
+
+  if len(fileSpecs) == 0 {
+    return nil, errors.New("bind provider requires at least one zone file")
+  }
+
+  for _, spec := range fileSpecs {
+    var origin string
+    path := spec
+    if separator := strings.Index(spec, "="); separator > 0 {
+      origin = spec[:separator]
+      path = spec[separator + 1:]
+    }
+
+    zf, err := ParseZoneFile(path, origin)
+    if err != nil {
+      return nil, err
+    }
+
+    z := new(zone)
+    z.id = strings.TrimSuffix(zf.origin, ".")
+    z.SetName(zf.origin)
+    if _, duplicate := p.recordsets[z.id]; duplicate {
+      return nil, fmt.Errorf("zone %s was found in more than one file", z.id)
+    }
+
+    zoneRecordset := make(recordset)
+    zoneRecordset.HashZoneFileRecords(zf.records)
+    recordCount := int64(0)
+    for _, records := range zoneRecordset {
+      recordCount += int64(len(records))
+    }
+    z.recordCount = &recordCount
+
+    p.recordsets[z.id] = &zoneRecordset
+    p.zones = append(p.zones, z)
+  }
+
+  return p, nil
+}
+func (p *bindProvider) GetZones() ([]*zone, error) {
+  return p.zones, nil
+}
+func (p *bindProvider) GetRecordsets(zoneId string) (*recordset, error) {
+  zoneRecordset, found := p.recordsets[strings.TrimSuffix(zoneId, ".")]
+  if !found {
+    return nil, fmt.Errorf("no zone file loaded for %s", zoneId)
+  }
+
+  return zoneRecordset, nil
+}
+func (p *bindProvider) Metadata() *providerMetadata {
+  return &providerMetadata{
+    description: "RFC 1035 master (zone) files",
+    name: "bind",
+  }
+}
@@ -0,0 +1,188 @@
+package main
+
+import(
+  "crypto/ecdsa"
+  "crypto/ed25519"
+  "crypto/rsa"
+  "crypto/sha256"
+  "crypto/x509"
+  "fmt"
+  "strings"
+  "time"
+)
+
+//roots used to build verified chains; nil is the system pool (tests swap it)
+var chainRoots *x509.CertPool
+
+/*
+ *  certificate chain - what a server presented, the chains crypto/x509 could
+ *  build from it and anything wrong with it (order, missing or expired
+ *  intermediates, an untrusted root)
+ */
+type chainReport struct {
+  issues []string
+  presented []*x509.Certificate
+  verified [][]*x509.Certificate
+  verifyError error
+}
+
+/*
+ *  Looks over the certificates a server presented (leaf first). Hostnames aren't
+ *  considered here, only whether the chain itself holds together at time now.
+ */
+func AnalyzeChain(presented []*x509.Certificate, roots *x509.CertPool, now time.Time) *chainReport {
+  report := &chainReport{presented: presented, issues: []string{}}
+
+  if len(presented) == 0 {
+    report.issues = append(report.issues, "no certificates presented")
+    return report
+  }
+
+  //order: the leaf comes first, then each certificate's issuer
+  leaf := LeafIndex(presented)
+  if leaf != 0 {
+    report.issues = append(report.issues, fmt.Sprintf("wrong order: the leaf certificate (%s) is at position %d, not first", presented[leaf].Subject.String(), leaf + 1))
+  }
+  for i := 0; i + 1 < len(presented); i++ {
+    if presented[i].CheckSignatureFrom(presented[i + 1]) != nil {
+      report.issues = append(report.issues, fmt.Sprintf("wrong order: certificate %d (%s) is not issued by the next certificate (%s)", i + 1, presented[i].Subject.String(), presented[i + 1].Subject.String()))
+    }
+  }
+
+  //validity of every certificate sent
+  for i, cert := range presented {
+    position := "intermediate"
+    if i == leaf {
+      position = "leaf"
+    } else if isSelfSigned(cert) {
+      position = "root"
+    }
+
+    if now.After(cert.NotAfter) {
+      report.issues = append(report.issues, fmt.Sprintf("expired %s: %s (expired %s)", position, cert.Subject.String(), cert.NotAfter.UTC().Format(time.RFC3339)))
+    } else if now.Before(cert.NotBefore) {
+      report.issues = append(report.issues, fmt.Sprintf("%s not yet valid: %s (valid from %s)", position, cert.Subject.String(), cert.NotBefore.UTC().Format(time.RFC3339)))
+    }
+  }
+
+  //build chains to a trusted root from what was sent
+  intermediates := x509.NewCertPool()
+  for i, cert := range presented {
+    if i != leaf {
+      intermediates.AddCert(cert)
+    }
+  }
+  report.verified, report.verifyError = presented[leaf].Verify(x509.VerifyOptions{
+    CurrentTime: now,
+    Intermediates: intermediates,
+    KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+    Roots: roots,
+  })
+
+  if report.verifyError != nil {
+    top := presented[len(presented) - 1]
+    if _, unknownAuthority := report.verifyError.(x509.UnknownAuthorityError); unknownAuthority {
+      if isSelfSigned(top) {
+        report.issues = append(report.issues, "untrusted root: " + top.Subject.String())
+      } else {
+        report.issues = append(report.issues, "incomplete chain: no certificate was sent for " + top.Issuer.String() + " (missing intermediate)")
+      }
+    } else if invalid, isInvalid := report.verifyError.(x509.CertificateInvalidError); !isInvalid || invalid.Reason != x509.Expired {
+      //expiry has been called out above, for whichever certificate it was
+      report.issues = append(report.issues, "chain doesn't verify: " + report.verifyError.Error())
+    }
+  }
+
+  return report
+}
+
+/*
+ *  Where the leaf is: the first certificate that isn't a CA, or the first one if
+ *  they all are.
+ */
+func LeafIndex(certs []*x509.Certificate) int {
+  for i, cert := range certs {
+    if !cert.IsCA {
+      return i
+    }
+  }
+
+  return 0
+}
+
+/*
+ *  a certificate that signed itself (a root)
+ */
+func isSelfSigned(cert *x509.Certificate) bool {
+  return cert.Subject.String() == cert.Issuer.String() && cert.CheckSignatureFrom(cert) == nil
+}
+
+/*
+ *  Key algorithm and size in bits.
+ */
+func PublicKeyInfo(cert *x509.Certificate) (string, int) {
+  switch key := cert.PublicKey.(type) {
+  case *rsa.PublicKey:
+    return "RSA", key.N.BitLen()
+  case *ecdsa.PublicKey:
+    return "ECDSA", key.Curve.Params().BitSize
+  case ed25519.PublicKey:
+    return "Ed25519", 256
+  }
+
+  return cert.PublicKeyAlgorithm.String(), 0
+}
+
+/*
+ *  colon separated hex, the way browsers show fingerprints and serials
+ */
+func colonHex(data []byte) string {
+  hexBytes := make([]string, len(data))
+
+  for i, b := range data {
+    hexBytes[i] = fmt.Sprintf("%02X", b)
+  }
+
+  return strings.Join(hexBytes, ":")
+}
+func sha256Fingerprint(cert *x509.Certificate) string {
+  sum := sha256.Sum256(cert.Raw)
+
+  return colonHex(sum[:])
+}
+
+func (report *chainReport) Output() *chainOutput {
+  output := &chainOutput{
+    Issues: nonNilStrings(report.issues),
+    Presented: certDetailOutputs(report.presented),
+    Verified: report.verifyError == nil && len(report.verified) > 0,
+    VerifiedChains: [][]*certDetailOutput{},
+  }
+
+  for _, chain := range report.verified {
+    output.VerifiedChains = append(output.VerifiedChains, certDetailOutputs(chain))
+  }
+
+  return output
+}
+func certDetailOutputs(certs []*x509.Certificate) []*certDetailOutput {
+  outputs := make([]*certDetailOutput, 0, len(certs))
+
+  for _, cert := range certs {
+    keyType, keySize := PublicKeyInfo(cert)
+    outputs = append(outputs, &certDetailOutput{
+      IsCA: cert.IsCA,
+      Issuer: cert.Issuer.String(),
+      KeySize: keySize,
+      KeyType: keyType,
+      NotAfter: cert.NotAfter.UTC(),
+      NotBefore: cert.NotBefore.UTC(),
+      SerialNumber: colonHex(cert.SerialNumber.Bytes()),
+      Sha256Fingerprint: sha256Fingerprint(cert),
+      SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+      Subject: cert.Subject.String(),
+    })
+  }
+
+  return outputs
+}
@@ -0,0 +1,190 @@
+package main
+import (
+  "crypto"
+  "crypto/ecdsa"
+  "crypto/elliptic"
+  "crypto/rand"
+  "crypto/rsa"
+  "crypto/tls"
+  "crypto/x509"
+  "crypto/x509/pkix"
+  "math/big"
+  "net/http"
+  "strings"
+  "testing"
+  "time"
+)
+
+/*
+ *  test certificate and its key
+ */
+type testCertificate struct {
+  cert *x509.Certificate
+  key crypto.Signer
+}
+
+/*
+ *  what can be changed about a test certificate; the zero value is an ECDSA
+ *  certificate with a random serial, usable for signing and as a CA
+ */
+type testCertificateOptions struct {
+  crlUrl string
+  dnsNames []string
+  keyUsage x509.KeyUsage
+  ocspServer string
+  //RSA key exchange suites need an RSA key
+  rsaKey bool
+  serial int64
+}
+
+/*
+ *  helper that issues a certificate; a nil parent makes it self-signed. CA
+ *  certificates get no names, leaves get dnsNames.
+ */
+func createTestCertificate(t *testing.T, commonName string, parent *testCertificate, isCA bool, notBefore time.Time, notAfter time.Time, dnsNames ...string) *testCertificate {
+  return createTestCertificateWith(t, commonName, parent, isCA, notBefore, notAfter, testCertificateOptions{dnsNames: dnsNames})
+}
+func createTestCertificateWith(t *testing.T, commonName string, parent *testCertificate, isCA bool, notBefore time.Time, notAfter time.Time, options testCertificateOptions) *testCertificate {
+  var key crypto.Signer
+  var err error
+  if options.rsaKey {
+    key, err = rsa.GenerateKey(rand.Reader, 2048)
+  } else {
+    key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+  }
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  serial, _ := rand.Int(rand.Reader, big.NewInt(1 << 62))
+  if options.serial > 0 {
+    serial = big.NewInt(options.serial)
+  }
+  template := &x509.Certificate{
+    BasicConstraintsValid: true,
+    DNSNames: options.dnsNames,
+    ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+    IsCA: isCA,
+    KeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+    NotAfter: notAfter,
+    NotBefore: notBefore,
+    SerialNumber: serial,
+    Subject: pkix.Name{CommonName: commonName},
+  }
+  if options.keyUsage != 0 {
+    template.KeyUsage = options.keyUsage
+  }
+  if len(options.ocspServer) > 0 {
+    template.OCSPServer = []string{options.ocspServer}
+  }
+  if len(options.crlUrl) > 0 {
+    template.CRLDistributionPoints = []string{options.crlUrl}
+  }
+
+  signer, signerKey := template, key
+  if parent != nil {
+    signer, signerKey = parent.cert, parent.key
+  }
+
+  der, err := x509.CreateCertificate(rand.Reader, template, signer, key.Public(), signerKey)
+  if err != nil {
+    t.Fatal(err)
+  }
+  cert, err := x509.ParseCertificate(der)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  return &testCertificate{cert: cert, key: key}
+}
+
+/*
+ *  a tls certificate serving leaf, followed by the rest of the chain
+ */
+func (leaf *testCertificate) TLSCertificate(chain ...*testCertificate) tls.Certificate {
+  certificate := tls.Certificate{Certificate: [][]byte{leaf.cert.Raw}, PrivateKey: leaf.key, Leaf: leaf.cert}
+
+  for _, cert := range chain {
+    certificate.Certificate = append(certificate.Certificate, cert.cert.Raw)
+  }
+
+  return certificate
+}
+
+/*
+ *  root -> intermediate -> leaf for www.example.com, all currently valid
+ */
+func createTestChain(t *testing.T) (*testCertificate, *testCertificate, *testCertificate) {
+  now := time.Now()
+  root := createTestCertificate(t, "Test Root", nil, true, now.Add(-time.Hour), now.Add(365 * 24 * time.Hour))
+  intermediate := createTestCertificate(t, "Test Intermediate", root, true, now.Add(-time.Hour), now.Add(180 * 24 * time.Hour))
+  leaf := createTestCertificate(t, "www.example.com", intermediate, false, now.Add(-time.Hour), now.Add(90 * 24 * time.Hour), "www.example.com", "*.example.com")
+
+  return root, intermediate, leaf
+}
+
+func TestAnalyzeChain(t *testing.T) {
+  now := time.Now()
+  root, intermediate, leaf := createTestChain(t)
+  expiredIntermediate := createTestCertificate(t, "Expired Intermediate", root, true, now.Add(-48 * time.Hour), now.Add(-24 * time.Hour))
+  expiredLeaf := createTestCertificate(t, "old.example.com", expiredIntermediate, false, now.Add(-48 * time.Hour), now.Add(24 * time.Hour), "old.example.com")
+  roots := x509.NewCertPool()
+  roots.AddCert(root.cert)
+  hasIssue := func(report *chainReport, prefix string) bool {
+    for _, issue := range report.issues {
+      if strings.HasPrefix(issue, prefix) {
+        return true
+      }
+    }
+    return false
+  }
+
+  //test cases
+  //  1: a complete chain verifies without issues
+  //  2: a missing intermediate is called out
+  //  3: the wrong order is called out (and the chain still verifies)
+  //  4: an expired intermediate is called out
+  //  5: a root nobody trusts is called out
+  //  6: each presented certificate's details are reported
+  tc1 := AnalyzeChain([]*x509.Certificate{leaf.cert, intermediate.cert}, roots, now)
+  if tc1.verifyError != nil || len(tc1.issues) != 0 || len(tc1.verified) != 1 || len(tc1.verified[0]) != 3 {
+    t.Errorf("tc1 - expected a verified chain: %v (error: %v)", tc1.issues, tc1.verifyError)
+  }
+
+  tc2 := AnalyzeChain([]*x509.Certificate{leaf.cert}, roots, now)
+  if tc2.verifyError == nil || !hasIssue(tc2, "incomplete chain: no certificate was sent for CN=Test Intermediate") {
+    t.Errorf("tc2 - expected a missing intermediate: %v", tc2.issues)
+  }
+
+  tc3 := AnalyzeChain([]*x509.Certificate{intermediate.cert, leaf.cert}, roots, now)
+  if tc3.verifyError != nil || !hasIssue(tc3, "wrong order: the leaf certificate (CN=www.example.com) is at position 2") {
+    t.Errorf("tc3 - expected the order to be called out: %v (error: %v)", tc3.issues, tc3.verifyError)
+  }
+
+  tc4 := AnalyzeChain([]*x509.Certificate{expiredLeaf.cert, expiredIntermediate.cert}, roots, now)
+  if tc4.verifyError == nil || !hasIssue(tc4, "expired intermediate: CN=Expired Intermediate") || len(tc4.issues) != 1 {
+    t.Errorf("tc4 - expected an expired intermediate: %v", tc4.issues)
+  }
+
+  tc5 := AnalyzeChain([]*x509.Certificate{leaf.cert, intermediate.cert, root.cert}, x509.NewCertPool(), now)
+  if !hasIssue(tc5, "untrusted root: CN=Test Root") {
+    t.Errorf("tc5 - expected an untrusted root: %v", tc5.issues)
+  }
+
+  details := tc1.Output().Presented
+  if len(details) != 2 || details[0].KeyType != "ECDSA" || details[0].KeySize != 256 || details[0].SignatureAlgorithm != "ECDSA-SHA256" || details[0].Issuer != "CN=Test Intermediate" || !details[1].IsCA || len(details[0].Sha256Fingerprint) != 95 {
+    t.Errorf("tc6 - unexpected certificate details: %+v", details[0])
+  }
+}
+
+func TestAnalyzeTLSCertificateAuthorities(t *testing.T) {
+  root, intermediate, _ := createTestChain(t)
+  res := &requestResult{rawResponse: &http.Response{TLS: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{intermediate.cert, root.cert}}}}
+
+  //test cases
+  //  1: a chain of nothing but CA certificates doesn't run past the end
+  res.AnalyzeTLS()
+  if res.certSubject != "CN=Test Intermediate" || res.chain == nil {
+    t.Errorf("tc1 - expected the first certificate to stand in for the leaf: %s", res.certSubject)
+  }
+}
@@ -0,0 +1,189 @@
+package main
+
+import(
+  "crypto/x509"
+  "net"
+  "net/url"
+  "strings"
+  "time"
+)
+
+//why verification failed, as reported in a site's verification.failures
+const(
+  verificationExpired string = "expired"
+  verificationHostnameMismatch string = "hostnameMismatch"
+  verificationInvalid string = "invalid"
+  verificationNotYetValid string = "notYetValid"
+  verificationUnknownAuthority string = "unknownAuthority"
+)
+
+/*
+ *  hostname coverage - whether a leaf certificate is valid for the hostname a
+ *  site was reached at, and which of its subject alternative names said so
+ */
+type hostnameReport struct {
+  hostname string
+  matchedNames []string
+  matchedWildcards []string
+  subjectAltNames []string
+}
+
+/*
+ *  verification - whether a site's certificate would be trusted, why not, and
+ *  whether the check only got through by not verifying it
+ */
+type verificationReport struct {
+  failures []string
+  insecureFallback bool
+  strictError error
+}
+
+/*
+ *  Matches hostname against the leaf's SANs (the common name isn't considered,
+ *  same as crypto/x509). IP addresses are matched against IP SANs. A wildcard
+ *  covers exactly one left-most label: *.example.com covers www.example.com but
+ *  neither example.com nor a.b.example.com.
+ */
+func MatchHostname(leaf *x509.Certificate, hostname string) *hostnameReport {
+  report := &hostnameReport{hostname: hostname, matchedNames: []string{}, matchedWildcards: []string{}, subjectAltNames: []string{}}
+  host := strings.ToLower(strings.TrimSuffix(hostname, "."))
+  hostIp := net.ParseIP(host)
+
+  for _, ip := range leaf.IPAddresses {
+    report.subjectAltNames = append(report.subjectAltNames, ip.String())
+    if hostIp != nil && ip.Equal(hostIp) {
+      report.matchedNames = append(report.matchedNames, ip.String())
+    }
+  }
+  for _, dnsName := range leaf.DNSNames {
+    report.subjectAltNames = append(report.subjectAltNames, dnsName)
+    if hostIp != nil || len(host) == 0 {
+      continue
+    }
+
+    name := strings.ToLower(strings.TrimSuffix(dnsName, "."))
+    if name == host {
+      report.matchedNames = append(report.matchedNames, dnsName)
+    } else if strings.HasPrefix(name, "*.") {
+      firstDot := strings.Index(host, ".")
+      if firstDot > 0 && host[firstDot + 1:] == name[2:] {
+        report.matchedWildcards = append(report.matchedWildcards, dnsName)
+      }
+    }
+  }
+
+  return report
+}
+func (report *hostnameReport) Covered() bool {
+  return len(report.matchedNames) > 0 || len(report.matchedWildcards) > 0
+}
+
+/*
+ *  Puts together every reason the leaf isn't trusted for hostname: its own
+ *  validity period, the chain (see AnalyzeChain) and hostname coverage (nil if
+ *  the hostname isn't known). When the strict request was refused but nothing
+ *  here explains it, the refusal itself is classified.
+ */
+func VerifySite(leaf *x509.Certificate, chain *chainReport, hostname *hostnameReport, strictError error, insecureFallback bool, now time.Time) *verificationReport {
+  report := &verificationReport{failures: []string{}, insecureFallback: insecureFallback, strictError: strictError}
+  addFailure := func(failure string) {
+    for _, existing := range report.failures {
+      if existing == failure {
+        return
+      }
+    }
+    report.failures = append(report.failures, failure)
+  }
+
+  if now.After(leaf.NotAfter) {
+    addFailure(verificationExpired)
+  } else if now.Before(leaf.NotBefore) {
+    addFailure(verificationNotYetValid)
+  }
+  if chain != nil && chain.verifyError != nil {
+    addFailure(VerificationFailure(chain.verifyError, leaf, now))
+  }
+  if hostname != nil && !hostname.Covered() {
+    addFailure(verificationHostnameMismatch)
+  }
+
+  if len(report.failures) == 0 && strictError != nil {
+    addFailure(VerificationFailure(strictError, leaf, now))
+  }
+
+  return report
+}
+
+/*
+ *  Names the reason in a verification error (as returned by x509 Verify, or
+ *  wrapped in a request error). x509 calls a certificate outside its validity
+ *  period expired either way; the leaf's dates tell the two apart, and one that
+ *  is in date means an intermediate expired.
+ */
+func VerificationFailure(err error, leaf *x509.Certificate, now time.Time) string {
+  for err != nil {
+    switch cause := err.(type) {
+    case x509.UnknownAuthorityError:
+      return verificationUnknownAuthority
+    case x509.HostnameError:
+      return verificationHostnameMismatch
+    case x509.CertificateInvalidError:
+      if cause.Reason != x509.Expired {
+        return verificationInvalid
+      }
+      if leaf != nil && now.Before(leaf.NotBefore) {
+        return verificationNotYetValid
+      }
+      return verificationExpired
+    case *url.Error:
+      err = cause.Err
+    case interface{ Unwrap() error }:
+      //newer tls versions wrap x509 errors in one of their own
+      err = cause.Unwrap()
+    default:
+      err = nil
+    }
+  }
+
+  return verificationInvalid
+}
+
+func (report *hostnameReport) Output() *hostnameOutput {
+  return &hostnameOutput{
+    Covered: report.Covered(),
+    Hostname: report.hostname,
+    MatchedNames: nonNilStrings(report.matchedNames),
+    MatchedWildcards: nonNilStrings(report.matchedWildcards),
+    SubjectAltNames: nonNilStrings(report.subjectAltNames),
+  }
+}
+func (report *verificationReport) Output() *verificationOutput {
+  output := &verificationOutput{
+    Failures: nonNilStrings(report.failures),
+    InsecureFallback: report.insecureFallback,
+    Verified: len(report.failures) == 0 && !report.insecureFallback,
+  }
+
+  if report.strictError != nil {
+    output.ErrorMessage = report.strictError.Error()
+  }
+
+  return output
+}
+
+/*
+ *  one line for table and csv output: "verified", or what failed (and whether
+ *  the check fell back to not verifying)
+ */
+func VerificationSummary(verification *verificationOutput) string {
+  summary := strings.Join(verification.Failures, ", ")
+
+  if verification.Verified {
+    return "verified"
+  }
+  if verification.InsecureFallback {
+    summary = strings.TrimSuffix("insecure fallback: " + summary, ": ")
+  }
+
+  return summary
+}
@@ -0,0 +1,120 @@
+package main
+import (
+  "crypto/x509"
+  "net"
+  "net/http"
+  "net/http/httptest"
+  "strings"
+  "testing"
+  "time"
+)
+
+func TestMatchHostname(t *testing.T) {
+  _, _, leaf := createTestChain(t)
+  ipLeaf := &x509.Certificate{IPAddresses: []net.IP{net.ParseIP("192.0.2.1")}, DNSNames: []string{"example.com"}}
+
+  //test cases
+  //  1: an exact SAN matches (case and trailing dot don't matter)
+  //  2: a wildcard covers a single label
+  //  3: a wildcard doesn't cover the apex or two labels down
+  //  4: an IP address matches an IP SAN, never a dns name
+  //  5: no SAN matching is a mismatch, and every SAN is listed
+  tc1 := MatchHostname(leaf.cert, "WWW.Example.com.")
+  if !tc1.Covered() || len(tc1.matchedNames) != 1 || tc1.matchedNames[0] != "www.example.com" || len(tc1.matchedWildcards) != 1 {
+    t.Errorf("tc1 - expected www.example.com (and the wildcard) to match: %v %v", tc1.matchedNames, tc1.matchedWildcards)
+  }
+
+  tc2 := MatchHostname(leaf.cert, "api.example.com")
+  if !tc2.Covered() || len(tc2.matchedNames) != 0 || len(tc2.matchedWildcards) != 1 || tc2.matchedWildcards[0] != "*.example.com" {
+    t.Errorf("tc2 - expected only the wildcard to match: %v %v", tc2.matchedNames, tc2.matchedWildcards)
+  }
+
+  for _, hostname := range []string{"example.com", "a.b.example.com"} {
+    if MatchHostname(leaf.cert, hostname).Covered() {
+      t.Errorf("tc3 - expected %s not to be covered", hostname)
+    }
+  }
+
+  tc4 := MatchHostname(ipLeaf, "192.0.2.1")
+  if !tc4.Covered() || tc4.matchedNames[0] != "192.0.2.1" || MatchHostname(ipLeaf, "192.0.2.2").Covered() {
+    t.Errorf("tc4 - expected the IP SAN to match: %v", tc4.matchedNames)
+  }
+
+  tc5 := MatchHostname(leaf.cert, "www.example.org")
+  if tc5.Covered() || strings.Join(tc5.subjectAltNames, " ") != "www.example.com *.example.com" {
+    t.Errorf("tc5 - expected a mismatch listing every SAN: %v", tc5.subjectAltNames)
+  }
+}
+
+func TestVerifySite(t *testing.T) {
+  now := time.Now()
+  root, intermediate, leaf := createTestChain(t)
+  expiredLeaf := createTestCertificate(t, "old.example.com", intermediate, false, now.Add(-48 * time.Hour), now.Add(-24 * time.Hour), "old.example.com")
+  futureLeaf := createTestCertificate(t, "new.example.com", intermediate, false, now.Add(24 * time.Hour), now.Add(48 * time.Hour), "new.example.com")
+  roots := x509.NewCertPool()
+  roots.AddCert(root.cert)
+  verify := func(leaf *testCertificate, hostname string, roots *x509.CertPool, strictError error, insecureFallback bool) *verificationOutput {
+    chain := AnalyzeChain([]*x509.Certificate{leaf.cert, intermediate.cert}, roots, now)
+    return VerifySite(leaf.cert, chain, MatchHostname(leaf.cert, hostname), strictError, insecureFallback, now).Output()
+  }
+
+  //test cases
+  //  1: a trusted chain covering the hostname verifies
+  //  2: an unknown authority, with the insecure fallback called out
+  //  3: an expired leaf
+  //  4: a leaf that isn't valid yet
+  //  5: a hostname mismatch
+  //  6: a refusal nothing else explains is classified from the request error
+  tc1 := verify(leaf, "www.example.com", roots, nil, false)
+  if !tc1.Verified || len(tc1.Failures) != 0 {
+    t.Errorf("tc1 - expected the site to verify: %v", tc1.Failures)
+  }
+
+  tc2 := verify(leaf, "www.example.com", x509.NewCertPool(), x509.UnknownAuthorityError{}, true)
+  if tc2.Verified || !tc2.InsecureFallback || strings.Join(tc2.Failures, ",") != "unknownAuthority" || len(tc2.ErrorMessage) == 0 {
+    t.Errorf("tc2 - expected an unknown authority: %+v", tc2)
+  }
+  if summary := VerificationSummary(tc2); summary != "insecure fallback: unknownAuthority" {
+    t.Errorf("tc2 - unexpected summary: %s", summary)
+  }
+
+  tc3 := verify(expiredLeaf, "old.example.com", roots, nil, false)
+  if tc3.Verified || strings.Join(tc3.Failures, ",") != "expired" {
+    t.Errorf("tc3 - expected an expired leaf: %v", tc3.Failures)
+  }
+
+  tc4 := verify(futureLeaf, "new.example.com", roots, nil, false)
+  if tc4.Verified || strings.Join(tc4.Failures, ",") != "notYetValid" {
+    t.Errorf("tc4 - expected a leaf that isn't valid yet: %v", tc4.Failures)
+  }
+
+  tc5 := verify(leaf, "example.com", roots, nil, false)
+  if tc5.Verified || strings.Join(tc5.Failures, ",") != "hostnameMismatch" {
+    t.Errorf("tc5 - expected a hostname mismatch: %v", tc5.Failures)
+  }
+
+  tc6 := verify(leaf, "www.example.com", roots, x509.HostnameError{Certificate: leaf.cert, Host: "www.example.com"}, true)
+  if tc6.Verified || strings.Join(tc6.Failures, ",") != "hostnameMismatch" {
+    t.Errorf("tc6 - expected the request error to be classified: %v", tc6.Failures)
+  }
+}
+
+func TestCheckSiteInsecureFallback(t *testing.T) {
+  server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+  defer server.Close()
+
+  //test cases
+  //  1: a certificate nobody trusts is only reached through the insecure
+  //     fallback, and the result says so
+  res := CheckSite(server.URL)
+  output := res.Output()
+  if res.callError != nil || output.Verification == nil || output.Hostname == nil {
+    t.Fatalf("tc1 - expected a verification report: %v", res.callError)
+  }
+  if output.Verification.Verified || !output.Verification.InsecureFallback || strings.Join(output.Verification.Failures, ",") != "unknownAuthority" {
+    t.Errorf("tc1 - expected an insecure fallback over an unknown authority: %+v", output.Verification)
+  }
+  if !output.Hostname.Covered || output.Hostname.Hostname != "127.0.0.1" {
+    t.Errorf("tc1 - expected the test certificate to cover 127.0.0.1: %+v", output.Hostname)
+  }
+}
@@ -0,0 +1,237 @@
+package main
+
+import(
+  "encoding/json"
+  "errors"
+  "fmt"
+  "net/http"
+  "net/url"
+  "strconv"
+  "strings"
+  "time"
+)
+
+//default location of the cloudflare v4 api
+const cloudflareApiBase string = "https://api.cloudflare.com/client/v4"
+//environment variable holding the api token
+const cloudflareTokenVariable string = "CLOUDFLARE_API_TOKEN"
+
+/*
+ *  cloudflare provider - zones and dns records from the cloudflare v4 REST api
+ *  see: https://developers.cloudflare.com/api/
+ */
+type cloudflareProvider struct {
+  apiBase string
+  client *http.Client
+  pageSize int
+  token string
+}
+
+/*
+ *  Creates a cloudflare backed provider. The base url is only worth changing for
+ *  testing; an empty string uses the public api.
+ */
+func NewCloudflareProvider(apiBase string, token string) *cloudflareProvider {
+  if len(apiBase) == 0 {
+    apiBase = cloudflareApiBase
+  }
+
+  return &cloudflareProvider{
+    apiBase: strings.TrimSuffix(apiBase, "/"),
+    client: &http.Client{Timeout: 30 * time.Second},
+    pageSize: 50,
+    token: token,
+  }
+}
+
+/*
+ *  envelope wrapped around every cloudflare api response
+ */
+type cloudflareResponse struct {
+  Errors []struct {
+    Code int `json:"code"`
+    Message string `json:"message"`
+  } `json:"errors"`
+  Result json.RawMessage `json:"result"`
+  ResultInfo struct {
+    Page int `json:"page"`
+    TotalCount int64 `json:"total_count"`
+    TotalPages int `json:"total_pages"`
+  } `json:"result_info"`
+  Success bool `json:"success"`
+}
+type cloudflareZone struct {
+  Id string `json:"id"`
+  Name string `json:"name"`
+}
+type cloudflareRecord struct {
+  Content string `json:"content"`
+  Data struct {
+    Port int `json:"port"`
+    Priority int `json:"priority"`
+    Target string `json:"target"`
+    Weight int `json:"weight"`
+  } `json:"data"`
+  Name string `json:"name"`
+  Priority int `json:"priority"`
+  Proxied bool `json:"proxied"`
+  Ttl int64 `json:"ttl"`
+  Type string `json:"type"`
+}
+
+/*
+ *  Makes a single GET request against the api and unwraps the response envelope.
+ */
+func (p *cloudflareProvider) get(path string, query url.Values) (*cloudflareResponse, error) {
+  var cfResp cloudflareResponse
+
+  req, err := http.NewRequest("GET", p.apiBase + path + "?" + query.Encode(), nil)
+  if err != nil {
+    return nil, err
+  }
+
+  req.Header.Set("Authorization", "Bearer " + p.token)
+  req.Header.Set("Content-Type", "application/json")
+  resp, err := p.client.Do(req)
+  if err != nil {
+    return nil, err
+  }
+
+  defer resp.Body.Close()
+  if err = json.NewDecoder(resp.Body).Decode(&cfResp); err != nil {
+    return nil, fmt.Errorf("unable to decode response (status %d): %s", resp.StatusCode, err.Error())
+  }
+
+  if !cfResp.Success {
+    if len(cfResp.Errors) > 0 {
+      return nil, fmt.Errorf("%s (code %d)", cfResp.Errors[0].Message, cfResp.Errors[0].Code)
+    }
+
+    return nil, errors.New("request was unsuccessful, status: " + resp.Status)
+  }
+
+  return &cfResp, nil
+}
+
+/*
+ *  Walks every page of a listing, handing each page's results to the collector.
+ */
+func (p *cloudflareProvider) getAllPages(path string, collect func(json.RawMessage) error) error {
+  var morePages bool = true
+  query := url.Values{}
+
+  query.Set("per_page", strconv.Itoa(p.pageSize))
+  for page := 1; morePages; page++ {
+    query.Set("page", strconv.Itoa(page))
+    cfResp, err := p.get(path, query)
+    if err != nil {
+      return err
+    }
+
+    if err = collect(cfResp.Result); err != nil {
+      return err
+    }
+
+    morePages = page < cfResp.ResultInfo.TotalPages
+  }
+
+  return nil
+}
+func (p *cloudflareProvider) GetZones() ([]*zone, error) {
+  var zones []*zone
+
+  err := p.getAllPages("/zones", func(result json.RawMessage) error {
+    var cfZones []cloudflareZone
+    if err := json.Unmarshal(result, &cfZones); err != nil {
+      return err
+    }
+
+    for _, cfZone := range cfZones {
+      z := new(zone)
+      z.id = cfZone.Id
+      z.SetName(cfZone.Name)
+      zones = append(zones, z)
+    }
+
+    return nil
+  })
+  if err != nil {
+    return nil, err
+  }
+
+  //the zone listing doesn't include record counts, and a request per zone to
+  //find them out isn't worth it; they're left out
+  return zones, nil
+}
+func (p *cloudflareProvider) GetRecordsets(zoneId string) (*recordset, error) {
+  var cfRecords []cloudflareRecord
+  zoneRecordset := make(recordset)
+
+  err := p.getAllPages("/zones/" + url.PathEscape(zoneId) + "/dns_records", func(result json.RawMessage) error {
+    var page []cloudflareRecord
+    if err := json.Unmarshal(result, &page); err != nil {
+      return err
+    }
+
+    cfRecords = append(cfRecords, page...)
+    return nil
+  })
+  if err != nil {
+    return nil, err
+  }
+
+  zoneRecordset.HashCloudflareRecords(cfRecords)
+
+  return &zoneRecordset, nil
+}
+func (p *cloudflareProvider) Metadata() *providerMetadata {
+  return &providerMetadata{
+    description: "Cloudflare DNS zones",
+    name: "cloudflare",
+  }
+}
+
+/*
+ *  Cloudflare returns one entry per value where route53 returns one entry per
+ *  name and type; group the values so records look the same from both providers.
+ */
+func (rset *recordset) HashCloudflareRecords(cfRecords []cloudflareRecord) {
+  grouped := make(map[string]*record)
+
+  for _, cfRecord := range cfRecords {
+    key := cfRecord.Type + " " + cfRecord.Name
+    currentRecordset, found := grouped[key]
+    if !found {
+      currentRecordset = new(record)
+      currentRecordset.rrType = cfRecord.Type
+      currentRecordset.name = cfRecord.Name
+      currentRecordset.ttl = cfRecord.Ttl
+      grouped[key] = currentRecordset
+      (*rset)[cfRecord.Type] = append((*rset)[cfRecord.Type], currentRecordset)
+    }
+
+    currentRecordset.proxied = currentRecordset.proxied || cfRecord.Proxied
+    currentRecordset.values = append(currentRecordset.values, cfRecord.RouteValue())
+  }
+}
+
+/*
+ *  Formats a record's value the way route53 presents it; cloudflare splits out
+ *  priorities and leaves TXT data unquoted.
+ */
+func (cfRecord *cloudflareRecord) RouteValue() string {
+  switch cfRecord.Type {
+  case "MX":
+    return strconv.Itoa(cfRecord.Priority) + " " + cfRecord.Content
+  case "SRV":
+    return fmt.Sprintf("%d %d %d %s", cfRecord.Data.Priority, cfRecord.Data.Weight, cfRecord.Data.Port, cfRecord.Data.Target)
+  case "TXT":
+    if strings.HasPrefix(cfRecord.Content, "\"") {
+      return cfRecord.Content
+    }
+
+    return QuoteCharacterString(cfRecord.Content)
+  }
+
+  return cfRecord.Content
+}
@@ -0,0 +1,98 @@
+package main
+import (
+  "fmt"
+  "net/http"
+  "net/http/httptest"
+  "strconv"
+  "sync/atomic"
+  "testing"
+)
+
+/*
+ *  stand-in for the cloudflare api; serves a single zone whose records are split
+ *  across two pages, counting the record listings asked for
+ */
+func createCloudflareServer(recordRequests *int32) *httptest.Server {
+  recordPages := []string{
+    `[{"type":"A","name":"www.example.com","content":"192.0.2.1","proxied":true},` +
+    `{"type":"MX","name":"example.com","content":"mail.example.com","priority":10}]`,
+    `[{"type":"A","name":"www.example.com","content":"192.0.2.2","proxied":false},` +
+    `{"type":"TXT","name":"example.com","content":"v=spf1 include:_spf.example.com ~all"}]`,
+  }
+  mux := http.NewServeMux()
+
+  mux.HandleFunc("/zones", func(w http.ResponseWriter, r *http.Request) {
+    if r.Header.Get("Authorization") != "Bearer test-token" {
+      w.WriteHeader(http.StatusForbidden)
+      fmt.Fprint(w, `{"success":false,"errors":[{"code":9109,"message":"Invalid access token"}]}`)
+      return
+    }
+
+    fmt.Fprint(w, `{"success":true,"result":[{"id":"zone1","name":"example.com"}],` +
+                  `"result_info":{"page":1,"total_pages":1,"total_count":1}}`)
+  })
+  mux.HandleFunc("/zones/zone1/dns_records", func(w http.ResponseWriter, r *http.Request) {
+    atomic.AddInt32(recordRequests, 1)
+    page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+    if page == 0 {
+      page = 1
+    }
+
+    fmt.Fprintf(w, `{"success":true,"result":%s,"result_info":{"page":%d,"total_pages":2,"total_count":4}}`, recordPages[page - 1], page)
+  })
+
+  return httptest.NewServer(mux)
+}
+
+func TestCloudflareProviderGetZones(t *testing.T) {
+  var recordRequests int32
+  server := createCloudflareServer(&recordRequests)
+  defer server.Close()
+
+  //test cases
+  //  1: zones are mapped onto the zone struct, without listing their records
+  //     (so there's no record count)
+  //  2: an invalid token surfaces the api's error message
+  zones, err := NewCloudflareProvider(server.URL, "test-token").GetZones()
+  if err != nil || len(zones) != 1 || zones[0].id != "zone1" || zones[0].DomainToString() != "example.com" || zones[0].recordCount != nil || atomic.LoadInt32(&recordRequests) != 0 {
+    t.Errorf("tc1 - unexpected zones (%d record requests): %+v (error: %v)", atomic.LoadInt32(&recordRequests), zones, err)
+  }
+
+  _, err = NewCloudflareProvider(server.URL, "bad-token").GetZones()
+  if err == nil || err.Error() != "Invalid access token (code 9109)" {
+    t.Errorf("tc2 - expected an invalid token error, received: %v", err)
+  }
+}
+
+func TestCloudflareProviderGetRecordsets(t *testing.T) {
+  var recordRequests int32
+  server := createCloudflareServer(&recordRequests)
+  defer server.Close()
+
+  //test cases
+  //  1: values for the same name and type (across pages) are grouped into one record
+  //  2: a record is proxied if any of its values are proxied
+  //  3: MX priority is folded into the value
+  //  4: TXT values are quoted like route53's
+  zoneRecords, err := NewCloudflareProvider(server.URL, "test-token").GetRecordsets("zone1")
+  if err != nil {
+    t.Fatalf("unexpected error: %s", err.Error())
+  }
+
+  aRecords := (*zoneRecords)["A"]
+  if len(aRecords) != 1 || len(aRecords[0].values) != 2 {
+    t.Fatalf("tc1 - expected one A record with two values: %+v", aRecords)
+  }
+
+  if !aRecords[0].proxied {
+    t.Errorf("tc2 - expected record to be proxied")
+  }
+
+  if mx := (*zoneRecords)["MX"]; len(mx) != 1 || mx[0].values[0] != "10 mail.example.com" {
+    t.Errorf("tc3 - unexpected MX record: %+v", mx)
+  }
+
+  if txt := (*zoneRecords)["TXT"]; len(txt) != 1 || txt[0].values[0] != "\"v=spf1 include:_spf.example.com ~all\"" {
+    t.Errorf("tc4 - unexpected TXT record: %+v", txt)
+  }
+}
@@ -0,0 +1,319 @@
+package main
+
+import(
+  "errors"
+  "fmt"
+  "net"
+  "sort"
+  "strings"
+  "time"
+
+  "github.com/miekg/dns"
+)
+
+/*
+ *  delegation checker - compares a zone's own NS recordset with what the parent
+ *  zone delegates to. The resolver (recursive) finds the parent's nameservers;
+ *  the parent's servers are then asked directly, without recursion.
+ */
+type delegationChecker struct {
+  client *dns.Client
+  port string
+  resolver string
+}
+
+/*
+ *  delegation report - one per zone
+ *  status is one of:
+ *    consistent  - the parent delegates to exactly the zone's nameservers
+ *    partial     - some nameservers are missing from one side or the other
+ *    mismatch    - no nameservers in common; this zone isn't the one being served
+ *    undelegated - the parent doesn't delegate the name at all
+ *    unknown     - the parent couldn't be asked (see error)
+ */
+type delegationReport struct {
+  delegatedNs []string
+  duplicateZoneIds []string
+  err error
+  extra []string
+  hostedNs []string
+  missing []string
+  status string
+  zoneId string
+  zoneName string
+}
+
+/*
+ *  Creates a checker using the given recursive resolver (host[:port]). An empty
+ *  resolver falls back to the first nameserver in /etc/resolv.conf.
+ */
+func NewDelegationChecker(resolver string) (*delegationChecker, error) {
+  if len(resolver) == 0 {
+    resolvConf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+    if err != nil || len(resolvConf.Servers) == 0 {
+      return nil, errors.New("no resolver given and none found in /etc/resolv.conf")
+    }
+    resolver = net.JoinHostPort(resolvConf.Servers[0], resolvConf.Port)
+  }
+
+  if _, _, err := net.SplitHostPort(resolver); err != nil {
+    resolver = net.JoinHostPort(resolver, "53")
+  }
+
+  return &delegationChecker{
+    client: &dns.Client{Timeout: 5 * time.Second},
+    port: "53",
+    resolver: resolver,
+  }, nil
+}
+
+/*
+ *  Checks the delegation of every zone the provider knows about (or just one when
+ *  zoneId is given, which has to exist). Zones sharing a name are called out as duplicates of each
+ *  other, whether or not they were checked; usually only one of them is actually
+ *  delegated.
+ */
+func (c *delegationChecker) CheckZones(provider dnsProvider, zoneId string) ([]*delegationReport, error) {
+  var reports []*delegationReport
+  zoneIdsByName := make(map[string][]string)
+
+  zones, err := provider.GetZones()
+  if err != nil {
+    return nil, err
+  }
+
+  //names of every zone, including the ones -domain leaves unchecked
+  for _, z := range zones {
+    name := normalizeNameserver(z.DomainToString())
+    zoneIdsByName[name] = append(zoneIdsByName[name], z.id)
+  }
+
+  for _, z := range zones {
+    if len(zoneId) > 0 && z.id != zoneId {
+      continue
+    }
+
+    zoneRecords, err := provider.GetRecordsets(z.id)
+    if err != nil {
+      return nil, err
+    }
+
+    reports = append(reports, c.CheckZone(z.id, zoneRecords))
+  }
+  if len(zoneId) > 0 && len(reports) == 0 {
+    return nil, fmt.Errorf("zone %s not found", zoneId)
+  }
+
+  for _, report := range reports {
+    for _, id := range zoneIdsByName[report.zoneName] {
+      if id != report.zoneId {
+        report.duplicateZoneIds = append(report.duplicateZoneIds, id)
+      }
+    }
+  }
+
+  return reports, nil
+}
+
+/*
+ *  Compares a single zone's apex NS recordset with the parent's delegation.
+ */
+func (c *delegationChecker) CheckZone(zoneId string, zoneRecords *recordset) *delegationReport {
+  report := &delegationReport{zoneId: zoneId, status: "unknown"}
+
+  //the SOA names the zone apex; the apex NS recordset is the zone's own view
+  if soa := (*zoneRecords)["SOA"]; len(soa) > 0 {
+    report.zoneName = normalizeNameserver(soa[0].name)
+  }
+  if len(report.zoneName) == 0 {
+    report.err = errors.New("zone has no SOA record")
+    return report
+  }
+
+  for _, ns := range (*zoneRecords)["NS"] {
+    if normalizeNameserver(ns.name) == report.zoneName {
+      for _, value := range ns.values {
+        report.hostedNs = append(report.hostedNs, normalizeNameserver(value))
+      }
+    }
+  }
+  sort.Strings(report.hostedNs)
+
+  report.delegatedNs, report.err = c.ParentDelegation(report.zoneName)
+  if report.err != nil {
+    return report
+  }
+
+  report.Compare()
+
+  return report
+}
+
+/*
+ *  Works out missing and extra nameservers and the overall status.
+ */
+func (report *delegationReport) Compare() {
+  hosted := make(map[string]bool)
+  delegated := make(map[string]bool)
+
+  report.missing, report.extra = nil, nil
+  for _, ns := range report.hostedNs {
+    hosted[ns] = true
+  }
+  for _, ns := range report.delegatedNs {
+    delegated[ns] = true
+    if !hosted[ns] {
+      report.missing = append(report.missing, ns)
+    }
+  }
+  for _, ns := range report.hostedNs {
+    if !delegated[ns] {
+      report.extra = append(report.extra, ns)
+    }
+  }
+
+  switch {
+  case len(report.delegatedNs) == 0:
+    report.status = "undelegated"
+  case len(report.missing) == 0 && len(report.extra) == 0:
+    report.status = "consistent"
+  case len(report.missing) == len(report.delegatedNs):
+    report.status = "mismatch"
+  default:
+    report.status = "partial"
+  }
+}
+
+/*
+ *  Asks the parent zone's nameservers which nameservers the zone is delegated to.
+ *  An empty (nil error) result means the parent has no delegation for the name.
+ */
+func (c *delegationChecker) ParentDelegation(zoneName string) ([]string, error) {
+  var lastErr error
+
+  parentNs, err := c.parentNameservers(zoneName)
+  if err != nil {
+    return nil, err
+  }
+
+  for _, host := range parentNs {
+    addresses, err := c.lookupAddresses(host)
+    if err != nil || len(addresses) == 0 {
+      lastErr = fmt.Errorf("unable to resolve parent nameserver %s", host)
+      continue
+    }
+
+    m := new(dns.Msg)
+    m.SetQuestion(dns.Fqdn(zoneName), dns.TypeNS)
+    m.RecursionDesired = false
+    resp, _, err := c.client.Exchange(m, net.JoinHostPort(addresses[0], c.port))
+    if err != nil {
+      lastErr = err
+      continue
+    }
+
+    if resp.Rcode == dns.RcodeNameError {
+      return nil, nil
+    }
+
+    //a referral carries the delegation in the authority section; a parent that
+    //also serves the child answers directly
+    delegation := nameserversFor(zoneName, resp.Ns)
+    if len(delegation) == 0 {
+      delegation = nameserversFor(zoneName, resp.Answer)
+    }
+    sort.Strings(delegation)
+
+    return delegation, nil
+  }
+
+  if lastErr == nil {
+    lastErr = errors.New("no parent nameservers found for " + zoneName)
+  }
+
+  return nil, lastErr
+}
+
+/*
+ *  Walks up from the zone's parent until a name with nameservers (a zone cut) is
+ *  found.
+ */
+func (c *delegationChecker) parentNameservers(zoneName string) ([]string, error) {
+  labels := dns.SplitDomainName(zoneName)
+
+  for i := 1; i < len(labels); i++ {
+    parent := strings.Join(labels[i:], ".")
+    resp, err := c.query(parent, dns.TypeNS)
+    if err != nil {
+      return nil, err
+    }
+
+    if nameservers := nameserversFor(parent, resp.Answer); len(nameservers) > 0 {
+      return nameservers, nil
+    }
+  }
+
+  return nil, errors.New("unable to find the parent zone of " + zoneName)
+}
+func (c *delegationChecker) lookupAddresses(host string) ([]string, error) {
+  var addresses []string
+
+  resp, err := c.query(host, dns.TypeA)
+  if err != nil {
+    return nil, err
+  }
+
+  for _, rr := range resp.Answer {
+    if a, isA := rr.(*dns.A); isA {
+      addresses = append(addresses, a.A.String())
+    }
+  }
+
+  return addresses, nil
+}
+func (c *delegationChecker) query(name string, qtype uint16) (*dns.Msg, error) {
+  m := new(dns.Msg)
+  m.SetQuestion(dns.Fqdn(name), qtype)
+
+  resp, _, err := c.client.Exchange(m, c.resolver)
+  return resp, err
+}
+
+/*
+ *  NS targets owned by name within a section of a response
+ */
+func nameserversFor(name string, section []dns.RR) []string {
+  var nameservers []string
+
+  for _, rr := range section {
+    if ns, isNs := rr.(*dns.NS); isNs && strings.EqualFold(normalizeNameserver(ns.Hdr.Name), normalizeNameserver(name)) {
+      nameservers = append(nameservers, normalizeNameserver(ns.Ns))
+    }
+  }
+
+  return nameservers
+}
+
+/*
+ *  nameserver names compare case-insensitively and without the trailing dot
+ */
+func normalizeNameserver(name string) string {
+  return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+func (report *delegationReport) Serialize() string {
+  return marshalJson(report.Output())
+}
+
+/*
+ * Function that serializes an array of delegation reports.
+ * loosely associated with delegationReport type
+ */
+func SerializeDelegations(reports []*delegationReport) string {
+  reportOutputs := make([]*delegationOutput, 0, len(reports))
+
+  for _, report := range reports {
+    reportOutputs = append(reportOutputs, report.Output())
+  }
+
+  return serializeDocument("delegations", reportOutputs)
+}
@@ -0,0 +1,137 @@
+package main
+import (
+  "net"
+  "testing"
+
+  "github.com/miekg/dns"
+)
+
+/*
+ *  stand-in resolver that is also the (only) nameserver for com.; it delegates
+ *  example.com. to ns1/ns2.example.net. and knows nothing of missing.com.
+ */
+func createDelegationServer(t *testing.T) (string, string, func()) {
+  rr := func(s string) dns.RR {
+    parsed, err := dns.NewRR(s)
+    if err != nil {
+      t.Fatal(err)
+    }
+    return parsed
+  }
+
+  handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+    m := new(dns.Msg)
+    m.SetReply(r)
+    q := r.Question[0]
+
+    switch {
+    case q.Name == "com." && q.Qtype == dns.TypeNS:
+      m.Answer = append(m.Answer, rr("com. 3600 IN NS ns.parent.test."))
+    case q.Name == "ns.parent.test." && q.Qtype == dns.TypeA:
+      m.Answer = append(m.Answer, rr("ns.parent.test. 3600 IN A 127.0.0.1"))
+    case q.Name == "example.com." && q.Qtype == dns.TypeNS && !r.RecursionDesired:
+      m.Ns = append(m.Ns, rr("example.com. 3600 IN NS ns1.example.net."), rr("example.com. 3600 IN NS ns2.example.net."))
+    case q.Name == "example.com.":
+      //recursive queries for the zone itself see the zone cut's SOA, not an NS set at com.
+      m.Ns = append(m.Ns, rr("example.com. 300 IN SOA ns1.example.net. hostmaster.example.com. 1 2 3 4 5"))
+    default:
+      m.SetRcode(r, dns.RcodeNameError)
+    }
+
+    w.WriteMsg(m)
+  })
+
+  conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  server := &dns.Server{PacketConn: conn, Handler: handler}
+  started := make(chan bool)
+  server.NotifyStartedFunc = func() { close(started) }
+  go server.ActivateAndServe()
+  <-started
+
+  _, port, _ := net.SplitHostPort(conn.LocalAddr().String())
+  return conn.LocalAddr().String(), port, func() { server.Shutdown() }
+}
+
+/*
+ *  helper for a zone's recordset with just an SOA and apex NS records
+ */
+func createDelegationRecordset(zoneName string, nameservers ...string) *recordset {
+  zoneRecordset := make(recordset)
+  zoneRecordset["SOA"] = []*record{&record{name: zoneName, values: []string{"ns1.example.net. hostmaster.example.com. 1 2 3 4 5"}}}
+  zoneRecordset["NS"] = []*record{&record{name: zoneName, values: nameservers}}
+
+  return &zoneRecordset
+}
+
+func TestDelegationCheckerCheckZone(t *testing.T) {
+  resolver, port, shutdown := createDelegationServer(t)
+  defer shutdown()
+  checker, _ := NewDelegationChecker(resolver)
+  checker.port = port
+
+  //test cases
+  //  1: matching nameservers (any case, trailing dots) are consistent
+  //  2: one nameserver missing from the zone is partial
+  //  3: no nameservers in common is a mismatch (shadow zone)
+  //  4: a name the parent doesn't know is undelegated
+  report := checker.CheckZone("Z1", createDelegationRecordset("example.com", "NS1.example.net.", "ns2.example.net."))
+  if report.err != nil || report.status != "consistent" {
+    t.Errorf("tc1 - expected consistent, received: %s (error: %v)", report.status, report.err)
+  }
+
+  report = checker.CheckZone("Z1", createDelegationRecordset("example.com", "ns1.example.net."))
+  if report.status != "partial" || len(report.missing) != 1 || report.missing[0] != "ns2.example.net" {
+    t.Errorf("tc2 - expected partial with ns2 missing, received: %s %v", report.status, report.missing)
+  }
+
+  report = checker.CheckZone("Z2", createDelegationRecordset("example.com", "ns-1.awsdns-01.org.", "ns-2.awsdns-02.com."))
+  if report.status != "mismatch" || len(report.extra) != 2 || len(report.missing) != 2 {
+    t.Errorf("tc3 - expected mismatch, received: %s (missing %v, extra %v)", report.status, report.missing, report.extra)
+  }
+
+  report = checker.CheckZone("Z3", createDelegationRecordset("missing.com", "ns1.example.net."))
+  if report.err != nil || report.status != "undelegated" {
+    t.Errorf("tc4 - expected undelegated, received: %s (error: %v)", report.status, report.err)
+  }
+}
+
+func TestDelegationCheckerDuplicates(t *testing.T) {
+  resolver, port, shutdown := createDelegationServer(t)
+  defer shutdown()
+  checker, _ := NewDelegationChecker(resolver)
+  checker.port = port
+  provider := &bindProvider{
+    recordsets: map[string]*recordset{
+      "Z1": createDelegationRecordset("example.com", "ns1.example.net.", "ns2.example.net."),
+      "Z2": createDelegationRecordset("example.com", "ns-1.awsdns-01.org."),
+    },
+    zones: []*zone{&zone{id: "Z1", domain: "example", tld: "com"}, &zone{id: "Z2", domain: "example", tld: "com"}},
+  }
+
+  //test cases
+  //  1: hosted zones with the same name point at each other as duplicates
+  //  2: only the delegated copy is consistent
+  //  3: checking one zone (-domain) still reports its duplicates
+  //  4: checking a zone that doesn't exist is an error
+  reports, err := checker.CheckZones(provider, "")
+  if err != nil || len(reports) != 2 || len(reports[0].duplicateZoneIds) != 1 || reports[0].duplicateZoneIds[0] != "Z2" {
+    t.Fatalf("tc1 - expected duplicates to be reported: %+v (error: %v)", reports, err)
+  }
+
+  if reports[0].status != "consistent" || reports[1].status != "mismatch" {
+    t.Errorf("tc2 - unexpected statuses: %s, %s", reports[0].status, reports[1].status)
+  }
+
+  reports, err = checker.CheckZones(provider, "Z2")
+  if err != nil || len(reports) != 1 || len(reports[0].duplicateZoneIds) != 1 || reports[0].duplicateZoneIds[0] != "Z1" {
+    t.Errorf("tc3 - expected Z1 to be reported as a duplicate of Z2: %+v (error: %v)", reports, err)
+  }
+
+  if _, err = checker.CheckZones(provider, "Z3"); err == nil || err.Error() != "zone Z3 not found" {
+    t.Errorf("tc4 - expected an unknown zone to be an error: %v", err)
+  }
+}
@@ -0,0 +1,191 @@
+package main
+
+import(
+  "fmt"
+  "io"
+  "os"
+  "strings"
+)
+
+/*
+ *  dns provider - anything that can hand us zones and the recordsets within them
+ *  route53 was the first (and for a while, only) implementation; zones and
+ *  recordsets coming from other providers should look just like route53's
+ */
+type dnsProvider interface {
+  //list every zone the provider knows about
+  GetZones() ([]*zone, error)
+  //list recordsets for a single zone (by zone id), bucketed by record type
+  GetRecordsets(zoneId string) (*recordset, error)
+  //information about the provider itself
+  Metadata() *providerMetadata
+}
+
+/*
+ *  providers able to hand over zones as they're fetched (rather than all at once)
+ *  can implement this; fn returning false stops the listing early
+ */
+type zoneStreamer interface {
+  EachZone(fn func(*zone) bool) error
+}
+
+/*
+ *  providers whose records can reference health checks implement this; looking
+ *  them up costs calls per check, so it's only done for record listings
+ */
+type healthCheckAttacher interface {
+  AttachHealthChecks(zoneId string, rset *recordset)
+}
+
+/*
+ *  provider metadata - describes where zones and records are coming from
+ */
+type providerMetadata struct {
+  description string
+  name string
+}
+
+/*
+ *  everything needed to construct a provider, gathered from program arguments
+ */
+type providerConfig struct {
+  accounts []*awsAccount
+  ixfrCache string
+  name string
+  nameserver string
+  pageSize int64
+  tsig string
+  zoneFiles []string
+  zoneNames []string
+}
+
+/*
+ *  Builds the provider named in the configuration. An empty name falls back to
+ *  route53 since that's what domania has always used.
+ */
+func NewDnsProvider(config *providerConfig) (dnsProvider, error) {
+  switch strings.ToLower(config.name) {
+  case "", "route53":
+    if len(config.accounts) > 0 {
+      return NewMultiAccountProvider(config.accounts, config.pageSize)
+    }
+
+    provider := NewRoute53Provider(nil)
+    provider.pageSize = config.pageSize
+    return provider, nil
+  case "cloudflare":
+    token := os.Getenv(cloudflareTokenVariable)
+    if len(token) == 0 {
+      return nil, fmt.Errorf("cloudflare provider requires an api token in %s", cloudflareTokenVariable)
+    }
+
+    return NewCloudflareProvider("", token), nil
+  case "bind":
+    return NewBindProvider(config.zoneFiles)
+  case "axfr":
+    var key *tsigKey
+    if len(config.tsig) > 0 {
+      var err error
+      if key, err = ParseTsigKey(config.tsig); err != nil {
+        return nil, err
+      }
+    }
+
+    return NewAxfrProvider(config.nameserver, config.zoneNames, key, config.ixfrCache)
+  }
+
+  return nil, fmt.Errorf("unknown dns provider \"%s\"", config.name)
+}
+
+/*
+ *  report a provider error in the same manner as service request errors; these
+ *  are always fatal since we can't continue without zones or records
+ */
+func HandleProviderError(provider dnsProvider, action string, err error) {
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "[Error] %s from provider %s...\n%s\n\n", action, provider.Metadata().name, err.Error())
+    os.Exit(1)
+  }
+}
+
+/*
+ *  Attaches health check status to a zone's records, if the provider has any.
+ */
+func AttachRecordHealthChecks(provider dnsProvider, zoneId string, rset *recordset) {
+  if attacher, hasHealthChecks := provider.(healthCheckAttacher); hasHealthChecks {
+    attacher.AttachHealthChecks(zoneId, rset)
+  }
+}
+
+/*
+ *  Hands each of a provider's zones to fn, streaming them if the provider can.
+ */
+func EachZone(provider dnsProvider, fn func(*zone) bool) error {
+  if streamer, canStream := provider.(zoneStreamer); canStream {
+    return streamer.EachZone(fn)
+  }
+
+  zones, err := provider.GetZones()
+  if err != nil {
+    return err
+  }
+
+  for _, z := range zones {
+    if !fn(z) {
+      break
+    }
+  }
+
+  return nil
+}
+
+/*
+ *  Looks a zone up by id among the provider's zones.
+ */
+func FindZone(provider dnsProvider, zoneId string) (*zone, error) {
+  var found *zone
+
+  err := EachZone(provider, func(z *zone) bool {
+    if z.id == zoneId {
+      found = z
+    }
+    return found == nil
+  })
+  if err != nil {
+    return nil, err
+  }
+
+  if found == nil {
+    return nil, fmt.Errorf("zone %s not found", zoneId)
+  }
+
+  return found, nil
+}
+
+/*
+ *  Writes zones with the formatter as the provider returns them, so large
+ *  accounts never have to be held in memory (unless the format needs it). The
+ *  output is closed off even if the provider fails part way through.
+ */
+func WriteZones(w io.Writer, provider dnsProvider, formatter outputFormatter) error {
+  var writeErr error
+
+  if writeErr = formatter.Begin(w, "zones"); writeErr != nil {
+    return writeErr
+  }
+
+  err := EachZone(provider, func(z *zone) bool {
+    writeErr = formatter.WriteItem(z.Output())
+    return writeErr == nil
+  })
+  if err != nil {
+    formatter.End()
+    return err
+  }
+
+  if writeErr != nil {
+    return writeErr
+  }
+
+  return formatter.End()
+}
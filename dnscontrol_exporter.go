@@ -0,0 +1,110 @@
+package main
+
+import(
+  "fmt"
+  "io"
+  "strconv"
+  "strings"
+)
+
+/*
+ *  dnscontrol exporter - a dnsconfig.js D() block for the zone. The registrar is
+ *  "none" and the dns provider is the creds.json entry "dns"; rename it to the
+ *  provider being migrated to. NAMESERVER() and the SOA are left to dnscontrol,
+ *  Route53 routing policies (which it can't express) are comments and aliases
+ *  become R53_ALIAS().
+ */
+type dnscontrolExporter struct {}
+func (e *dnscontrolExporter) Extension() string {
+  return "js"
+}
+func (e *dnscontrolExporter) Export(w io.Writer, z *zone, zoneRecords *recordset) error {
+  var out strings.Builder
+  var lines []string
+  origin := strings.TrimSuffix(z.DomainToString(), ".")
+
+  for _, rec := range SortedRecords(zoneRecords, origin) {
+    owner := RelativeOwnerName(rec.name, origin)
+    if rec.rrType == "SOA" || (rec.rrType == "NS" && owner == "@" && !rec.isAlias) {
+      continue
+    }
+
+    lines = append(lines, e.record(rec, owner)...)
+  }
+
+  fmt.Fprintf(&out, "// %s (zone %s)\n", origin, z.id)
+  out.WriteString("var REG_NONE = NewRegistrar(\"none\");\n")
+  out.WriteString("var DSP = NewDnsProvider(\"dns\");\n\n")
+  fmt.Fprintf(&out, "D(%s, REG_NONE, DnsProvider(DSP),\n", marshalJson(origin))
+  for _, line := range lines {
+    if strings.HasPrefix(line, "//") {
+      fmt.Fprintf(&out, "  %s\n", line)
+    } else {
+      fmt.Fprintf(&out, "  %s,\n", line)
+    }
+  }
+  //END lets every record line end in a comma
+  out.WriteString("END);\n")
+
+  _, err := io.WriteString(w, out.String())
+  return err
+}
+
+/*
+ *  dnscontrol calls for a record, one per value (or a comment)
+ */
+func (e *dnscontrolExporter) record(rec *record, owner string) []string {
+  var lines []string
+  name := marshalJson(owner)
+  modifiers := ", TTL(" + strconv.FormatInt(rec.ttl, 10) + ")"
+
+  if routing := rec.RoutingToString(); len(routing) > 0 {
+    return []string{"// " + rec.rrType + " " + owner + " with a routing policy (" + routing + "): " + strings.Join(rec.values, ", ")}
+  }
+
+  if rec.isAlias {
+    target := marshalJson(strings.Join(rec.values, " "))
+    alias := "R53_ALIAS(" + name + ", " + marshalJson(rec.rrType) + ", " + target + ", R53_ZONE(" + marshalJson(rec.zoneRef) + ")"
+    if rec.evaluateTargetHealth {
+      alias += ", R53_EVALUATE_TARGET_HEALTH(true)"
+    }
+    return []string{alias + ")"}
+  }
+
+  if rec.proxied {
+    modifiers += ", CF_PROXY_ON"
+  }
+
+  for _, value := range rec.values {
+    parsed := ParseRecordValue(rec.rrType, value)
+
+    switch {
+    case rec.rrType == "A" || rec.rrType == "AAAA":
+      lines = append(lines, rec.rrType + "(" + name + ", " + marshalJson(value) + modifiers + ")")
+    case rec.rrType == "CNAME" || rec.rrType == "NS" || rec.rrType == "PTR":
+      lines = append(lines, rec.rrType + "(" + name + ", " + marshalJson(AbsoluteRecordValue(rec.rrType, value)) + modifiers + ")")
+    case parsed.mx != nil:
+      lines = append(lines, fmt.Sprintf("MX(%s, %d, %s%s)", name, parsed.mx.preference, marshalJson(AbsoluteRecordValue("CNAME", parsed.mx.exchange)), modifiers))
+    case parsed.srv != nil:
+      lines = append(lines, fmt.Sprintf("SRV(%s, %d, %d, %d, %s%s)", name, parsed.srv.priority, parsed.srv.weight, parsed.srv.port, marshalJson(AbsoluteRecordValue("CNAME", parsed.srv.target)), modifiers))
+    case parsed.caa != nil:
+      caaModifiers := modifiers
+      //bit 128 is the only flag defined
+      if parsed.caa.flag & 128 != 0 {
+        caaModifiers += ", CAA_CRITICAL"
+      }
+      lines = append(lines, fmt.Sprintf("CAA(%s, %s, %s%s)", name, marshalJson(parsed.caa.tag), marshalJson(parsed.caa.value), caaModifiers))
+    case rec.rrType == "TXT" && parsed.characterStrings != nil:
+      //several character-strings are passed as an array so they stay separate
+      text := marshalJson(parsed.Text())
+      if len(parsed.characterStrings) > 1 {
+        text = marshalJson(parsed.characterStrings)
+      }
+      lines = append(lines, "TXT(" + name + ", " + text + modifiers + ")")
+    default:
+      lines = append(lines, "// " + rec.rrType + " " + owner + " isn't exported: " + value)
+    }
+  }
+
+  return lines
+}
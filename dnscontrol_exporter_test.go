@@ -0,0 +1,40 @@
+package main
+import (
+  "strings"
+  "testing"
+)
+
+func TestDnscontrolExporterExport(t *testing.T) {
+  var output strings.Builder
+  z, zoneRecords := createExportZone()
+  (*zoneRecords)["SRV"] = []*record{&record{name: "_sip._tcp.example.com", ttl: 300, values: []string{"10 60 5060 sip.example.com"}}}
+  (*zoneRecords)["CAA"][0].values = append((*zoneRecords)["CAA"][0].values, "128 issuewild \";\"")
+
+  //test cases
+  //  1: a D() block for the zone ending in END
+  //  2: SOA and apex NS are left to dnscontrol
+  //  3: records are dnscontrol calls with their ttl; names in rdata are absolute
+  //  4: TXT character-strings stay separate, CAA flags become CAA_CRITICAL
+  //  5: aliases become R53_ALIAS, routing policies are comments
+  err := new(dnscontrolExporter).Export(&output, z, zoneRecords)
+  js := output.String()
+  if err != nil || !strings.Contains(js, "D(\"example.com\", REG_NONE, DnsProvider(DSP),\n") || !strings.HasSuffix(js, "\nEND);\n") {
+    t.Errorf("tc1 - unexpected domain: %s (error: %v)", js, err)
+  }
+
+  if strings.Contains(js, "SOA") || strings.Contains(js, "awsdns") {
+    t.Errorf("tc2 - expected SOA and apex NS to be left out: %s", js)
+  }
+
+  if !strings.Contains(js, "  MX(\"@\", 10, \"mail.example.com.\", TTL(300)),\n") || !strings.Contains(js, "  SRV(\"_sip._tcp\", 10, 60, 5060, \"sip.example.com.\", TTL(300)),\n") || !strings.Contains(js, "  NS(\"dev\", \"ns1.dev.example.net.\", TTL(300)),\n") || !strings.Contains(js, "  A(\"*\", \"192.0.2.9\", TTL(300)),\n") {
+    t.Errorf("tc3 - unexpected records: %s", js)
+  }
+
+  if !strings.Contains(js, "  TXT(\"@\", [\"v=spf1 a mx; ~all\",\"say \\\"hi\\\", \\\\ \\ttab\"], TTL(300)),\n") || !strings.Contains(js, "  CAA(\"@\", \"issuewild\", \";\", TTL(300), CAA_CRITICAL),\n") {
+    t.Errorf("tc4 - unexpected TXT and CAA records: %s", js)
+  }
+
+  if !strings.Contains(js, "  R53_ALIAS(\"@\", \"A\", \"dualstack.lb.amazonaws.com.\", R53_ZONE(\"Z35SXDOTRQ7X7K\"), R53_EVALUATE_TARGET_HEALTH(true)),\n") || !strings.Contains(js, "  // A www with a routing policy (weighted: blue, weight 10): 192.0.2.1\n") {
+    t.Errorf("tc5 - unexpected alias and routing policy: %s", js)
+  }
+}
@@ -0,0 +1,156 @@
+package main
+
+import(
+  "strconv"
+  "strings"
+  "time"
+
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/route53"
+  "github.com/aws/aws-sdk-go/service/route53/route53iface"
+)
+
+//route53 considers an endpoint healthy when more than this share of checkers agree
+const healthyCheckerThreshold float64 = 0.18
+
+/*
+ *  health check summary - what a route53 health check watches and what each
+ *  checker region currently thinks of it
+ */
+type healthCheckSummary struct {
+  checkType string
+  healthyCheckers int
+  id string
+  inverted bool
+  observations []*healthCheckObservation
+  status string
+  target string
+}
+
+/*
+ *  a single checker region's most recent result
+ */
+type healthCheckObservation struct {
+  checkedAt time.Time
+  healthy bool
+  ipAddress string
+  region string
+  report string
+}
+
+/*
+ *  Fetches a health check's configuration and its status from every checker
+ *  region. Status is "healthy", "unhealthy" or "unknown" (calculated and
+ *  cloudwatch checks don't report per-region status).
+ */
+func GetHealthCheckSummary(svc route53iface.Route53API, healthCheckId string) (*healthCheckSummary, *awsRequest) {
+  var configResp *route53.GetHealthCheckOutput
+  var statusResp *route53.GetHealthCheckStatusOutput
+  summary := &healthCheckSummary{id: healthCheckId, status: "unknown"}
+  req := new(awsRequest)
+
+  //init request metadata; a missing health check shouldn't stop the record listing
+  req.serviceName = "route53"
+  req.serviceFunction = "GetHealthCheck"
+  req.fatalOnError = false
+  //exec api call and handle error
+  configResp, req.err = svc.GetHealthCheck(&route53.GetHealthCheckInput{HealthCheckId: aws.String(healthCheckId)})
+  req.HandleServiceRequestError()
+  if req.err != nil {
+    return summary, req
+  }
+
+  config := configResp.HealthCheck.HealthCheckConfig
+  summary.checkType = aws.StringValue(config.Type)
+  summary.inverted = aws.BoolValue(config.Inverted)
+  summary.target = healthCheckTarget(config)
+  if summary.checkType == route53.HealthCheckTypeCalculated || strings.HasPrefix(summary.checkType, "CLOUDWATCH") {
+    return summary, req
+  }
+
+  req.serviceFunction = "GetHealthCheckStatus"
+  statusResp, req.err = svc.GetHealthCheckStatus(&route53.GetHealthCheckStatusInput{HealthCheckId: aws.String(healthCheckId)})
+  req.HandleServiceRequestError()
+  if req.err != nil {
+    return summary, req
+  }
+
+  for _, checker := range statusResp.HealthCheckObservations {
+    observation := &healthCheckObservation{
+      ipAddress: aws.StringValue(checker.IPAddress),
+      region: aws.StringValue(checker.Region),
+    }
+    if checker.StatusReport != nil {
+      observation.checkedAt = aws.TimeValue(checker.StatusReport.CheckedTime)
+      observation.report = aws.StringValue(checker.StatusReport.Status)
+      observation.healthy = strings.HasPrefix(observation.report, "Success")
+    }
+
+    if observation.healthy {
+      summary.healthyCheckers++
+    }
+    summary.observations = append(summary.observations, observation)
+  }
+
+  if len(summary.observations) > 0 {
+    healthy := float64(summary.healthyCheckers) / float64(len(summary.observations)) > healthyCheckerThreshold
+    //an inverted check is healthy when the endpoint isn't
+    if healthy != summary.inverted {
+      summary.status = "healthy"
+    } else {
+      summary.status = "unhealthy"
+    }
+  }
+
+  return summary, req
+}
+
+/*
+ *  describes what a health check is pointed at, eg. HTTPS://www.example.com:443/health
+ */
+func healthCheckTarget(config *route53.HealthCheckConfig) string {
+  var target strings.Builder
+  host := aws.StringValue(config.FullyQualifiedDomainName)
+
+  if len(host) == 0 {
+    host = aws.StringValue(config.IPAddress)
+  }
+
+  if len(host) == 0 {
+    return ""
+  }
+
+  target.WriteString(strings.TrimSuffix(aws.StringValue(config.Type), "_STR_MATCH") + "://" + host)
+  if config.Port != nil {
+    target.WriteString(":" + strconv.FormatInt(*config.Port, 10))
+  }
+  target.WriteString(aws.StringValue(config.ResourcePath))
+
+  return target.String()
+}
+
+/*
+ *  Looks up every health check referenced by the recordset (once per check) and
+ *  attaches the summary to the records referencing it.
+ */
+func AttachHealthChecks(svc route53iface.Route53API, rset *recordset) {
+  summaries := make(map[string]*healthCheckSummary)
+
+  for _, records := range *rset {
+    for _, rec := range records {
+      if len(rec.healthCheckId) == 0 {
+        continue
+      }
+
+      summary, found := summaries[rec.healthCheckId]
+      if !found {
+        summary, _ = GetHealthCheckSummary(svc, rec.healthCheckId)
+        summaries[rec.healthCheckId] = summary
+      }
+      rec.healthCheck = summary
+    }
+  }
+}
+func (hc *healthCheckSummary) Serialize() string {
+  return marshalJson(hc.Output())
+}
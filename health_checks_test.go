@@ -0,0 +1,116 @@
+package main
+import (
+  "encoding/json"
+  "testing"
+  "time"
+
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/route53"
+)
+
+/*
+ *  health check responses for the route53 stand-in (see: route53_provider_test.go)
+ */
+func (m *mockRoute53) GetHealthCheck(in *route53.GetHealthCheckInput) (*route53.GetHealthCheckOutput, error) {
+  m.healthCheckCalls++
+  return &route53.GetHealthCheckOutput{
+    HealthCheck: &route53.HealthCheck{
+      Id: in.HealthCheckId,
+      HealthCheckConfig: &route53.HealthCheckConfig{
+        Type: aws.String("HTTPS"),
+        FullyQualifiedDomainName: aws.String("primary.example.com"),
+        Port: aws.Int64(443),
+        ResourcePath: aws.String("/health"),
+      },
+    },
+  }, nil
+}
+func (m *mockRoute53) GetHealthCheckStatus(in *route53.GetHealthCheckStatusInput) (*route53.GetHealthCheckStatusOutput, error) {
+  var observations []*route53.HealthCheckObservation
+  for region, report := range m.checkerReports {
+    observations = append(observations, &route53.HealthCheckObservation{
+      Region: aws.String(region),
+      IPAddress: aws.String("198.51.100.1"),
+      StatusReport: &route53.StatusReport{
+        Status: aws.String(report),
+        CheckedTime: aws.Time(time.Now()),
+      },
+    })
+  }
+
+  return &route53.GetHealthCheckStatusOutput{HealthCheckObservations: observations}, nil
+}
+
+func TestGetHealthCheckSummary(t *testing.T) {
+  failing := &mockRoute53{checkerReports: map[string]string{
+    "us-east-1": "Failure: Connection timed out.",
+    "eu-west-1": "Failure: Connection timed out.",
+    "ap-southeast-1": "Failure: HTTP Status Code 503, Service Unavailable.",
+  }}
+  passing := &mockRoute53{checkerReports: map[string]string{
+    "us-east-1": "Success: HTTP Status Code 200, OK",
+    "eu-west-1": "Failure: Connection timed out.",
+  }}
+
+  //test cases
+  //  1: configuration is summarized as a target
+  //  2: every checker failing is unhealthy
+  //  3: enough healthy checkers is healthy
+  summary, _ := GetHealthCheckSummary(failing, "hc-1")
+  if summary.target != "HTTPS://primary.example.com:443/health" || len(summary.observations) != 3 {
+    t.Errorf("tc1 - unexpected summary: %+v", summary)
+  }
+
+  if summary.status != "unhealthy" || summary.healthyCheckers != 0 {
+    t.Errorf("tc2 - expected unhealthy, received: %s (%d healthy)", summary.status, summary.healthyCheckers)
+  }
+
+  summary, _ = GetHealthCheckSummary(passing, "hc-2")
+  if summary.status != "healthy" || summary.healthyCheckers != 1 {
+    t.Errorf("tc3 - expected healthy, received: %s (%d healthy)", summary.status, summary.healthyCheckers)
+  }
+}
+
+func TestRoute53ProviderAttachesHealthChecks(t *testing.T) {
+  var jsonObject map[string]interface{}
+  failover := func(role string, healthCheckId string) *route53.ResourceRecordSet {
+    rrset := createRoute53Recordset("www.example.com.", "A", "192.0.2.1")
+    rrset.Failover = aws.String(role)
+    rrset.SetIdentifier = aws.String(role)
+    if len(healthCheckId) > 0 {
+      rrset.HealthCheckId = aws.String(healthCheckId)
+    }
+    return rrset
+  }
+  svc := &mockRoute53{
+    checkerReports: map[string]string{"us-east-1": "Failure: Connection refused."},
+    recordPages: [][]*route53.ResourceRecordSet{
+      []*route53.ResourceRecordSet{failover("PRIMARY", "hc-1"), failover("SECONDARY", ""), failover("PRIMARY", "hc-1")},
+    },
+  }
+
+  //test cases
+  //  1: records referencing a health check carry its summary; others don't
+  //  2: each health check is only looked up once, and only when attached
+  //  3: the summary is part of the serialized record
+  provider := NewRoute53Provider(svc)
+  zoneRecords, _ := provider.GetRecordsets("Z1EXAMPLE")
+  if svc.healthCheckCalls != 0 {
+    t.Errorf("tc2 - expected fetching records not to look up health checks, received: %d", svc.healthCheckCalls)
+  }
+  AttachRecordHealthChecks(provider, "Z1EXAMPLE", zoneRecords)
+  primary, secondary := (*zoneRecords)["A"][0], (*zoneRecords)["A"][1]
+  if primary.healthCheck == nil || primary.healthCheck.status != "unhealthy" || secondary.healthCheck != nil {
+    t.Errorf("tc1 - unexpected health checks: %+v, %+v", primary.healthCheck, secondary.healthCheck)
+  }
+
+  if svc.healthCheckCalls != 1 {
+    t.Errorf("tc2 - expected one health check lookup, received: %d", svc.healthCheckCalls)
+  }
+
+  serialized := primary.Serialize()
+  json.Unmarshal([]byte(serialized), &jsonObject)
+  if healthCheck, found := jsonObject["healthCheck"].(map[string]interface{}); !found || healthCheck["status"] != "unhealthy" {
+    t.Errorf("tc3 - expected health check in serialized record: %s", serialized)
+  }
+}
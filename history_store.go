@@ -0,0 +1,419 @@
+package main
+
+import(
+  "bytes"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "os"
+  "strings"
+  "time"
+
+  bolt "go.etcd.io/bbolt"
+)
+
+//buckets in the history database
+var(
+  historyRunsBucket = []byte("runs")
+  historyZoneRunsBucket = []byte("zoneRuns")
+  historyRecordsBucket = []byte("records")
+  historySitesBucket = []byte("sites")
+)
+
+//timestamps in keys are fixed width so keys sort by time
+const historyTimeLayout string = "20060102T150405.000000000Z"
+
+/*
+ *  history store - results of automatable runs kept in a local bbolt database so
+ *  they can be searched later. Keys are built so a cursor finds everything about
+ *  one name (or site) together, oldest first:
+ *    runs      time                                    -> run
+ *    zoneRuns  zone id, time                           -> (empty)
+ *    records   name, zone id, type, set identifier, time -> record
+ *    sites     site, time                              -> site check
+ */
+type historyStore struct {
+  db *bolt.DB
+}
+type runOutput struct {
+  StartedAt time.Time `json:"startedAt"`
+  Arguments []string `json:"arguments"`
+}
+
+/*
+ *  one version of a record: how it looked (or that it was gone) from the first
+ *  run that saw it that way to the last
+ */
+type recordVersionOutput struct {
+  ZoneId string `json:"zoneId"`
+  Name string `json:"name"`
+  Type string `json:"type"`
+  SetIdentifier string `json:"setIdentifier,omitempty"`
+  Present bool `json:"present"`
+  FirstSeen time.Time `json:"firstSeen"`
+  LastSeen time.Time `json:"lastSeen"`
+  Observations int `json:"observations"`
+  Record *recordOutput `json:"record,omitempty"`
+}
+
+/*
+ *  a site check field that differs from the previous successful check
+ */
+type siteChangeOutput struct {
+  Site string `json:"site"`
+  Field string `json:"field"`
+  Before string `json:"before"`
+  After string `json:"after"`
+  LastSeenBefore time.Time `json:"lastSeenBefore"`
+  ChangedAt time.Time `json:"changedAt"`
+}
+
+/*
+ *  Opens (or creates) the history database. Scheduled runs that overlap wait a
+ *  few seconds for the file lock rather than failing straight away.
+ */
+func OpenHistoryStore(path string) (*historyStore, error) {
+  db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+  if err != nil {
+    return nil, fmt.Errorf("opening history %s: %s", path, err.Error())
+  }
+
+  err = db.Update(func(tx *bolt.Tx) error {
+    for _, bucket := range [][]byte{historyRunsBucket, historyZoneRunsBucket, historyRecordsBucket, historySitesBucket} {
+      if _, bucketErr := tx.CreateBucketIfNotExists(bucket); bucketErr != nil {
+        return bucketErr
+      }
+    }
+    return nil
+  })
+  if err != nil {
+    db.Close()
+    return nil, err
+  }
+
+  return &historyStore{db: db}, nil
+}
+func (store *historyStore) Close() error {
+  return store.db.Close()
+}
+
+func (store *historyStore) SaveRun(startedAt time.Time, arguments []string) error {
+  value, _ := json.Marshal(&runOutput{Arguments: nonNilStrings(arguments), StartedAt: startedAt.UTC()})
+
+  return store.db.Update(func(tx *bolt.Tx) error {
+    return tx.Bucket(historyRunsBucket).Put([]byte(historyTime(startedAt)), value)
+  })
+}
+
+/*
+ *  Saves all of a zone's records as seen at observedAt. The zone's run is kept
+ *  too, so a record missing from a later run can be reported as removed.
+ */
+func (store *historyStore) SaveRecords(observedAt time.Time, zoneId string, zoneRecords *recordset) error {
+  observed := historyTime(observedAt)
+
+  return store.db.Update(func(tx *bolt.Tx) error {
+    if err := tx.Bucket(historyZoneRunsBucket).Put(historyKey(zoneId, observed), []byte{}); err != nil {
+      return err
+    }
+
+    for _, rec := range SortedRecords(zoneRecords, "") {
+      //like snapshots, health check status isn't part of a record's history
+      output := rec.Output()
+      output.HealthCheck = nil
+      output.ParsedValues = nil
+      value, _ := json.Marshal(output)
+      if err := tx.Bucket(historyRecordsBucket).Put(historyKey(historyName(rec.name), zoneId, rec.rrType, rec.setIdentifier, observed), value); err != nil {
+        return err
+      }
+    }
+
+    return nil
+  })
+}
+
+/*
+ *  Saves a site check under the name that was probed; the result's own site is
+ *  wherever the check ended up (after redirects), which changes along with the
+ *  redirect.
+ */
+func (store *historyStore) SaveSite(observedAt time.Time, site string, result *requestResult) error {
+  value, _ := json.Marshal(result.Output())
+
+  return store.db.Update(func(tx *bolt.Tx) error {
+    return tx.Bucket(historySitesBucket).Put(historyKey(historyName(site), historyTime(observedAt)), value)
+  })
+}
+
+/*
+ *  Wraps a site check so each result is saved as it comes back. A result that
+ *  can't be saved is still returned; the error goes to stderr.
+ */
+func (store *historyStore) RecordingCheck(observedAt time.Time, check func(string) *requestResult) func(string) *requestResult {
+  return func(site string) *requestResult {
+    result := check(site)
+    if err := store.SaveSite(observedAt, site, result); err != nil {
+      HandleHistoryError(err)
+    }
+
+    return result
+  }
+}
+
+/*
+ *  Answers a -query: runs, records:<name>, certs[:<site>] (fingerprint changes)
+ *  or redirects[:<site>] (sites that stopped redirecting to https). Returns the
+ *  collection name and items for WriteCollection.
+ */
+func (store *historyStore) Query(query string) (string, []interface{}, error) {
+  var items []interface{}
+  kind, argument := query, ""
+  if separator := strings.Index(query, ":"); separator >= 0 {
+    kind, argument = query[:separator], query[separator + 1:]
+  }
+
+  switch strings.ToLower(kind) {
+  case "runs":
+    runs, err := store.Runs()
+    for _, run := range runs {
+      items = append(items, run)
+    }
+    return "runs", items, err
+  case "records":
+    if len(argument) == 0 {
+      return "", nil, errors.New("records query needs a name (records:www.example.com)")
+    }
+    versions, err := store.RecordHistory(argument)
+    for _, version := range versions {
+      items = append(items, version)
+    }
+    return "recordHistory", items, err
+  case "certs", "redirects":
+    field := "certFingerprint"
+    if strings.ToLower(kind) == "redirects" {
+      field = "redirectsToHttps"
+    }
+    changes, err := store.SiteChanges(argument, field)
+    for _, change := range changes {
+      //for redirects, only the sites that stopped
+      if field == "redirectsToHttps" && change.After != "false" {
+        continue
+      }
+      items = append(items, change)
+    }
+    return "siteChanges", items, err
+  }
+
+  return "", nil, fmt.Errorf("unknown history query \"%s\" (runs, records:<name>, certs[:<site>], redirects[:<site>])", query)
+}
+
+func (store *historyStore) Runs() ([]*runOutput, error) {
+  var runs []*runOutput
+
+  err := store.db.View(func(tx *bolt.Tx) error {
+    return tx.Bucket(historyRunsBucket).ForEach(func(key []byte, value []byte) error {
+      run := new(runOutput)
+      if err := json.Unmarshal(value, run); err != nil {
+        return err
+      }
+      runs = append(runs, run)
+      return nil
+    })
+  })
+
+  return runs, err
+}
+
+/*
+ *  Every version of the records with this name, in each zone that has it: runs
+ *  that saw the record unchanged are merged, and runs of the zone that didn't
+ *  find it show up as a version that isn't present.
+ */
+func (store *historyStore) RecordHistory(name string) ([]*recordVersionOutput, error) {
+  var versions []*recordVersionOutput
+  observations := make(map[string]map[string][]byte)
+  var groups []string
+
+  err := store.db.View(func(tx *bolt.Tx) error {
+    prefix := historyKey(historyName(name), "")
+    cursor := tx.Bucket(historyRecordsBucket).Cursor()
+    for key, value := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+      //zone id, type, set identifier and time after the name
+      parts := strings.Split(string(key[len(prefix):]), "\x00")
+      group := strings.Join(parts[:3], "\x00")
+      if observations[group] == nil {
+        observations[group] = make(map[string][]byte)
+        groups = append(groups, group)
+      }
+      observations[group][parts[3]] = append([]byte{}, value...)
+    }
+
+    for _, group := range groups {
+      parts := strings.Split(group, "\x00")
+      groupVersions, groupErr := recordVersions(tx, parts[0], parts[1], observations[group])
+      if groupErr != nil {
+        return groupErr
+      }
+      versions = append(versions, groupVersions...)
+    }
+
+    return nil
+  })
+
+  return versions, err
+}
+
+/*
+ *  walks a zone's runs for one record, merging runs where it looked the same
+ */
+func recordVersions(tx *bolt.Tx, zoneId string, recordType string, observations map[string][]byte) ([]*recordVersionOutput, error) {
+  var versions []*recordVersionOutput
+  var runs []string
+  var current *recordVersionOutput
+  var currentValue []byte
+
+  prefix := historyKey(zoneId, "")
+  cursor := tx.Bucket(historyZoneRunsBucket).Cursor()
+  for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+    runs = append(runs, string(key[len(prefix):]))
+  }
+
+  for _, run := range runs {
+    value, present := observations[run]
+    //nothing to say about the runs before the record first appeared
+    if current == nil && !present {
+      continue
+    }
+
+    observedAt, _ := time.Parse(historyTimeLayout, run)
+    if current != nil && current.Present == present && bytes.Equal(currentValue, value) {
+      current.LastSeen = observedAt
+      current.Observations++
+      continue
+    }
+
+    current = &recordVersionOutput{FirstSeen: observedAt, LastSeen: observedAt, Observations: 1, Present: present, Type: recordType, ZoneId: zoneId}
+    currentValue = value
+    if present {
+      current.Record = new(recordOutput)
+      if err := json.Unmarshal(value, current.Record); err != nil {
+        return nil, err
+      }
+    }
+    versions = append(versions, current)
+  }
+
+  //every version carries the record's identity, even the absent ones
+  for _, version := range versions {
+    for _, other := range versions {
+      if other.Record != nil {
+        version.Name, version.SetIdentifier = other.Record.Name, other.Record.SetIdentifier
+        break
+      }
+    }
+  }
+
+  return versions, nil
+}
+
+/*
+ *  Changes to one field of site checks (certFingerprint or redirectsToHttps)
+ *  from one successful check to the next, for one site or (site empty) all of
+ *  them. Failed checks are skipped; an unreachable site hasn't changed its
+ *  certificate.
+ */
+func (store *historyStore) SiteChanges(site string, field string) ([]*siteChangeOutput, error) {
+  var changes []*siteChangeOutput
+  var lastSite, lastValue string
+  var lastSeen time.Time
+
+  err := store.db.View(func(tx *bolt.Tx) error {
+    var prefix []byte
+    if len(site) > 0 {
+      prefix = historyKey(historyName(site), "")
+    }
+
+    cursor := tx.Bucket(historySitesBucket).Cursor()
+    for key, value := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+      var check siteOutput
+      parts := strings.Split(string(key), "\x00")
+      if err := json.Unmarshal(value, &check); err != nil {
+        return err
+      }
+      if check.Error {
+        continue
+      }
+
+      observedAt, _ := time.Parse(historyTimeLayout, parts[1])
+      fieldValue := fmt.Sprintf("%t", check.RedirectsToHttps)
+      if field == "certFingerprint" {
+        fieldValue = ""
+        if check.Cert != nil {
+          fieldValue = check.Cert.Fingerprint
+        }
+      }
+
+      if parts[0] == lastSite && fieldValue != lastValue {
+        changes = append(changes, &siteChangeOutput{After: fieldValue, Before: lastValue, ChangedAt: observedAt, Field: field, LastSeenBefore: lastSeen, Site: parts[0]})
+      }
+      lastSite, lastValue, lastSeen = parts[0], fieldValue, observedAt
+    }
+
+    return nil
+  })
+
+  return changes, err
+}
+
+/*
+ *  prints history errors; saving history never stops a run
+ */
+func HandleHistoryError(err error) {
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "[Error] saving history: %s\n", err.Error())
+  }
+}
+
+func historyTime(t time.Time) string {
+  return t.UTC().Format(historyTimeLayout)
+}
+func historyKey(parts ...string) []byte {
+  return []byte(strings.Join(parts, "\x00"))
+}
+func historyName(name string) string {
+  return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+func (run *runOutput) Columns() []string {
+  return []string{"startedAt", "arguments"}
+}
+func (run *runOutput) Rows() [][]string {
+  return [][]string{{run.StartedAt.Format(time.RFC3339), strings.Join(run.Arguments, " ")}}
+}
+func (version *recordVersionOutput) Columns() []string {
+  return []string{"zoneId", "name", "type", "setIdentifier", "firstSeen", "lastSeen", "observations", "ttl", "value"}
+}
+func (version *recordVersionOutput) Rows() [][]string {
+  row := []string{version.ZoneId, version.Name, version.Type, version.SetIdentifier, version.FirstSeen.Format(time.RFC3339), version.LastSeen.Format(time.RFC3339), fmt.Sprintf("%d", version.Observations), "", "(removed)"}
+  if version.Record == nil {
+    return [][]string{row}
+  }
+
+  var rows [][]string
+  if version.Record.Ttl != nil {
+    row[7] = fmt.Sprintf("%d", *version.Record.Ttl)
+  }
+  for _, value := range version.Record.Values {
+    valueRow := append([]string{}, row...)
+    valueRow[8] = value
+    rows = append(rows, valueRow)
+  }
+
+  return rows
+}
+func (change *siteChangeOutput) Columns() []string {
+  return []string{"site", "field", "before", "after", "lastSeenBefore", "changedAt"}
+}
+func (change *siteChangeOutput) Rows() [][]string {
+  return [][]string{{change.Site, change.Field, change.Before, change.After, change.LastSeenBefore.Format(time.RFC3339), change.ChangedAt.Format(time.RFC3339)}}
+}
@@ -0,0 +1,161 @@
+package main
+import (
+  "encoding/json"
+  "io/ioutil"
+  "net/http"
+  "os"
+  "path/filepath"
+  "testing"
+  "time"
+)
+
+/*
+ *  helper that opens a history database in a temporary directory
+ */
+func createHistoryStore(t *testing.T) (*historyStore, func()) {
+  dir, err := ioutil.TempDir("", "domania-history")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  store, err := OpenHistoryStore(filepath.Join(dir, "history.db"))
+  if err != nil {
+    os.RemoveAll(dir)
+    t.Fatal(err)
+  }
+
+  return store, func() {
+    store.Close()
+    os.RemoveAll(dir)
+  }
+}
+
+func TestHistoryStoreRecordHistory(t *testing.T) {
+  store, cleanup := createHistoryStore(t)
+  defer cleanup()
+  start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+  run := func(hour int, www *record) {
+    zoneRecords := recordset{"MX": []*record{&record{name: "example.com", ttl: 300, values: []string{"10 mail.example.com."}}}}
+    if www != nil {
+      zoneRecords["A"] = []*record{www}
+    }
+    if err := store.SaveRecords(start.Add(time.Duration(hour) * time.Hour), "Z1", &zoneRecords); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  run(0, nil)
+  run(1, &record{name: "www.example.com", ttl: 300, values: []string{"192.0.2.1"}})
+  run(2, &record{name: "www.example.com", ttl: 300, values: []string{"192.0.2.1"}})
+  run(3, &record{name: "www.example.com", ttl: 60, values: []string{"192.0.2.2"}})
+  run(4, nil)
+
+  //test cases
+  //  1: runs that saw the same record are merged; runs before it existed are left out
+  //  2: a change starts a new version
+  //  3: a run without the record is a version that isn't present
+  //  4: names are matched without regard to case or a trailing dot
+  versions, err := store.RecordHistory("WWW.example.com.")
+  if err != nil || len(versions) != 3 {
+    t.Fatalf("tc1 - expected 3 versions, received: %d (error: %v)", len(versions), err)
+  }
+  if !versions[0].FirstSeen.Equal(start.Add(time.Hour)) || !versions[0].LastSeen.Equal(start.Add(2 * time.Hour)) || versions[0].Observations != 2 || versions[0].Record.Values[0] != "192.0.2.1" {
+    t.Errorf("tc1 - unexpected first version: %+v", versions[0])
+  }
+
+  if *versions[1].Record.Ttl != 60 || versions[1].Record.Values[0] != "192.0.2.2" || !versions[1].Present {
+    t.Errorf("tc2 - unexpected second version: %+v", versions[1])
+  }
+
+  if versions[2].Present || versions[2].Record != nil || versions[2].Name != "www.example.com" || versions[2].Type != "A" {
+    t.Errorf("tc3 - unexpected last version: %+v", versions[2])
+  }
+
+  if mx, _ := store.RecordHistory("example.com"); len(mx) != 1 || mx[0].Observations != 5 {
+    t.Errorf("tc4 - expected one unchanged MX version, received: %+v", mx)
+  }
+}
+
+func TestHistoryStoreSiteChanges(t *testing.T) {
+  store, cleanup := createHistoryStore(t)
+  defer cleanup()
+  start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+  check := func(hour int, site string, fingerprint string, redirects bool, failed bool) {
+    result := &requestResult{site: site, redirectsToHttps: redirects, responseEncrypted: true, certFingerprint: fingerprint, rawResponse: &http.Response{StatusCode: 200}}
+    if failed {
+      result = &requestResult{site: site, callError: os.ErrDeadlineExceeded}
+    }
+    recording := store.RecordingCheck(start.Add(time.Duration(hour) * time.Hour), func(string) *requestResult { return result })
+    recording(site)
+  }
+
+  check(0, "www.example.com", "aa", true, false)
+  check(0, "api.example.com", "cc", true, false)
+  check(1, "www.example.com", "", false, true)
+  check(1, "api.example.com", "cc", true, false)
+  check(2, "www.example.com", "bb", true, false)
+  check(2, "api.example.com", "cc", false, false)
+
+  //test cases
+  //  1: a fingerprint change is reported once, failed checks in between are skipped
+  //  2: redirects only lists sites that stopped redirecting
+  //  3: queries can be limited to one site
+  //  4: query results are documents that follow the schema
+  //  5: checks are kept under the probed name, not the url a redirect led to
+  collection, items, err := store.Query("certs")
+  if err != nil || collection != "siteChanges" || len(items) != 1 {
+    t.Fatalf("tc1 - expected one certificate change, received: %d (error: %v)", len(items), err)
+  }
+  change := items[0].(*siteChangeOutput)
+  if change.Site != "www.example.com" || change.Before != "aa" || change.After != "bb" || !change.LastSeenBefore.Equal(start) || !change.ChangedAt.Equal(start.Add(2 * time.Hour)) {
+    t.Errorf("tc1 - unexpected change: %+v", change)
+  }
+
+  _, items, _ = store.Query("redirects")
+  if len(items) != 1 || items[0].(*siteChangeOutput).Site != "api.example.com" {
+    t.Errorf("tc2 - expected api.example.com to have stopped redirecting: %+v", items)
+  }
+
+  if _, items, _ = store.Query("certs:api.example.com"); len(items) != 0 {
+    t.Errorf("tc3 - expected no certificate changes for api.example.com: %+v", items)
+  }
+
+  var schema, document map[string]interface{}
+  json.Unmarshal([]byte(outputSchema), &schema)
+  json.Unmarshal([]byte(serializeDocument("siteChanges", []*siteChangeOutput{change})), &document)
+  if err = validateSchema(schema, schema["$defs"].(map[string]interface{}), document, "$"); err != nil {
+    t.Errorf("tc4 - %s", err.Error())
+  }
+
+  for hour, redirecting := range []bool{true, false} {
+    mock := CreateTest("http://shop.example.com", 200, false)
+    if redirecting {
+      mock = CreateTest("http://shop.example.com", 301, true)
+    }
+    result := &requestResult{responseEncrypted: true, certFingerprint: "dd", rawResponse: mock.response}
+    result.redirects, result.redirectsToHttps, result.site = CheckForRedirection(mock.response)
+    store.RecordingCheck(start.Add(time.Duration(hour) * time.Hour), func(string) *requestResult { return result })("shop.example.com")
+  }
+  if _, items, _ = store.Query("redirects:shop.example.com"); len(items) != 1 || items[0].(*siteChangeOutput).Site != "shop.example.com" {
+    t.Errorf("tc5 - expected shop.example.com to have stopped redirecting: %+v", items)
+  }
+}
+
+func TestHistoryStoreRuns(t *testing.T) {
+  store, cleanup := createHistoryStore(t)
+  defer cleanup()
+  startedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+  //test cases
+  //  1: runs are listed with their arguments
+  //  2: unknown queries are an error
+  store.SaveRun(startedAt, []string{"-a", "-c", "-domain", "Z1"})
+  collection, items, err := store.Query("runs")
+  if err != nil || collection != "runs" || len(items) != 1 || len(items[0].(*runOutput).Arguments) != 4 || !items[0].(*runOutput).StartedAt.Equal(startedAt) {
+    t.Errorf("tc1 - unexpected runs: %+v (error: %v)", items, err)
+  }
+
+  if _, _, err = store.Query("everything"); err == nil {
+    t.Error("tc2 - expected an error for an unknown query")
+  }
+}
@@ -3,12 +3,12 @@ package main
 import(
   "flag"
   "fmt"
+  "io/ioutil"
   "os"
+  "os/signal"
   "strings"
-
-  "github.com/aws/aws-sdk-go/aws"
-  "github.com/aws/aws-sdk-go/aws/session"
-  "github.com/aws/aws-sdk-go/service/route53"
+  "syscall"
+  "time"
 )
 
 
@@ -26,81 +26,9 @@ func domaniaUsage() {
   os.Exit(0)
 }
 
-func GetHostedZones(svc *route53.Route53, args *route53.ListHostedZonesInput) ([]*zone, *awsRequest) {
-  var resp *route53.ListHostedZonesOutput
-  var zones []*zone
-  req := new(awsRequest)
-
-  //init request metadata
-  req.serviceName = "route53"
-  req.serviceFunction = "ListHostedZones"
-  req.fatalOnError = true
-  //exec api call and handle error
-  resp, req.err = svc.ListHostedZones(args)
-  req.HandleServiceRequestError()
-
-  zones = make([]*zone, len(resp.HostedZones))
-  //hold results in custom struct array
-  for i:=0; i<len(resp.HostedZones); i++ {
-    currentZone := resp.HostedZones[i]
-    currentName := string(*currentZone.Name)[:len(*currentZone.Name)-1]
-    z := new(zone)
-
-    //only the last part of the zone id is relevant
-    z.id = strings.Split(*currentZone.Id,"/")[2]
-    //separate out the domain (eg. example.com -> |example|com|)
-    if len(strings.Split(currentName, ".")) > 1 {
-      z.domain = strings.Split(currentName, ".")[0]
-      z.tld = strings.Split(currentName, ".")[1]
-    } else {
-      z.domain = currentName
-    }
-
-    z.recordCount = *currentZone.ResourceRecordSetCount
-    zones[i] = z
-  }
-
-  return zones, req
-}
-
-func GetRecordsetsForZone(svc *route53.Route53, zoneId string) (*recordset, *awsRequest) {
-  var args = &route53.ListResourceRecordSetsInput{
-    HostedZoneId: aws.String(zoneId),
-    //using the following doesn't work... we'll just filter in memory (*sigh*)
-    //StartRecordName: aws.String("*"),
-    //StartRecordType: aws.String("A"),
-  }
-  var moreRecords bool = true
-  var resp *route53.ListResourceRecordSetsOutput
-  req := new(awsRequest)
-  zoneRecordset := make(recordset)
-
-  //init request metadata
-  req.serviceName = "route53"
-  req.serviceFunction = "ListResourceRecordSets"
-  req.fatalOnError = true
-  //handle paginated results
-  for moreRecords {
-    //exec api call and handle error
-    resp, req.err = svc.ListResourceRecordSets(args)
-    req.HandleServiceRequestError()
-    //in-memory filter
-    zoneRecordset.HashRecordsetTypes(resp.ResourceRecordSets)
-    if resp != nil && *resp.IsTruncated {
-      args.SetStartRecordName(*resp.NextRecordName)
-      moreRecords = true
-    } else {
-      moreRecords = false
-    }
-  }
-
-  //don't pass what could be a massive amount of data, just the reference
-  return &zoneRecordset, req
-}
-
 /*
- *  custom bubble sort for hosted zones; can sort by the domain or tld field for
- *  an array of hosted zones
+ *  custom bubble sort for hosted zones; can sort by the subdomain, domain or tld
+ *  field for an array of hosted zones
  */
 func HzSort(domainContainers []*zone, sortTarget string) {
   var sorted bool = false
@@ -110,12 +38,15 @@ func HzSort(domainContainers []*zone, sortTarget string) {
     for i:=1; i<len(domainContainers); i++ {
       pos := 0
       continueComparison := true
-      //let's begin by looking at each struct's sortable field (either "domain" or "tld")
+      //let's begin by looking at each struct's sortable field ("subdomain", "domain" or "tld")
       behind := strings.ToLower(domainContainers[i-1].domain)
       current := strings.ToLower(domainContainers[i].domain)
       if sortTarget == "tld" {
         behind = strings.ToLower(domainContainers[i-1].tld)
         current = strings.ToLower(domainContainers[i].tld)
+      } else if sortTarget == "subdomain" {
+        behind = strings.ToLower(domainContainers[i-1].subdomain)
+        current = strings.ToLower(domainContainers[i].subdomain)
       }
 
       //lexicographical comparison; could've used sort.Strings() but, these strings are relatively simple
@@ -141,6 +72,14 @@ func HzSort(domainContainers []*zone, sortTarget string) {
           continueComparison = false
         }
       }//end word comparison
+
+      //one word is the start of the other (eg. no subdomain vs. "dev"); shorter goes first
+      if continueComparison && len(behind) > len(current) {
+        temp := domainContainers[i - 1]
+        domainContainers[i - 1] = domainContainers[i]
+        domainContainers[i] = temp
+        sorted = false
+      }
     }
   } //end sort iteration
 }
@@ -163,40 +102,142 @@ func (req *awsRequest) HandleServiceRequestError() {
   }
 }
 
+/*
+ *  splits a comma separated argument; an empty argument is an empty list
+ */
+func splitList(argument string) []string {
+  if len(argument) == 0 {
+    return nil
+  }
+
+  return strings.Split(argument, ",")
+}
+
 func main() {
+  var accountsFile string
+  var config *providerConfig = new(providerConfig)
   var domainId string
   var moreInput bool = true
+  var profiles string
+  var publicSuffixFile string
   var resourceRecord string
+  var roleArns string
+  var transferZones string
   var userResponse string
+  var zoneFiles string
 
   //--- program arguments ---
   //program mode can be: (interactive || automatable)
   autoMode := flag.Bool("a", false, "mode: automatable and silent; use this option for single queries")
   //investigate content served by domains
   checkDomainContent := flag.Bool("c", false, "gathers secure protocol information on content served within a domain")
+  warnDays := flag.Int("warn-days", 30, "with -c, certificates expiring within this many days are a warning (exit code 1)")
+  critDays := flag.Int("crit-days", 7, "with -c, certificates expiring within this many days, or expired, are critical (exit code 2)")
+  deepTls := flag.Bool("deep-tls", false, "with -c, also lists every tls version and cipher suite each https site accepts (one connection per suite)")
+  noRevocation := flag.Bool("no-revocation", false, "with -c, doesn't ask OCSP responders or CRL distribution points whether certificates without a stapled response were revoked")
+  //registrar side of things
+  registrarInventory := flag.Bool("r", false, "lists domains registered through route53 domains (expiry, renewal, locks, nameservers)")
+  //are zones actually delegated to the nameservers they list?
+  checkDelegation := flag.Bool("delegation", false, "compares each zone's NS records with the parent zone's delegation")
+  resolver := flag.String("resolver", "", "recursive resolver (host[:port]) for delegation checks; defaults to /etc/resolv.conf")
+  //what changed in dns?
+  snapshotFile := flag.String("snapshot", "", "saves every zone and record to a snapshot file (- for stdout)")
+  diffFiles := flag.String("diff", "", "compares snapshots: before.json,after.json, or a single snapshot against the provider's live state")
+  //results of automatable runs, kept for later queries
+  historyFile := flag.String("history", "", "bbolt database that records and site checks from automatable runs are saved to")
+  historyQuery := flag.String("query", "", "searches -history: runs, records:<name>, certs[:<site>] or redirects[:<site>]")
+  //zone file backups
+  exportFormat := flag.String("export", "", "writes zones in another format: bind, terraform, octodns or dnscontrol; to stdout with -domain, otherwise a file per zone in -export-dir")
+  exportDir := flag.String("export-dir", ".", "directory zones are exported into when -domain isn't given")
+  //what the output looks like
+  outputFormat := flag.String("o", "", "output format: json, ndjson, table, csv or yaml (default json in automatable mode, table in interactive mode)")
+  printSchema := flag.Bool("schema", false, "prints the JSON Schema describing automatable mode output, then exits")
   flag.StringVar(&domainId, "domain", "", "identifier for a hosted zone")
+  flag.StringVar(&config.name, "provider", "route53", "dns provider supplying zones and records: route53, cloudflare, bind, axfr")
+  flag.StringVar(&profiles, "profiles", "", "comma separated aws profiles to scan (route53)")
+  flag.StringVar(&roleArns, "roles", "", "comma separated role arns to assume and scan (route53)")
+  flag.StringVar(&accountsFile, "accounts", "", "JSON file of aws accounts to scan (profiles, roles, external ids, mfa)")
+  flag.StringVar(&zoneFiles, "zonefile", "", "comma separated zone files for the bind provider, optionally as origin=path")
+  flag.StringVar(&transferZones, "zones", "", "comma separated zones to transfer for the axfr provider")
+  flag.StringVar(&config.nameserver, "nameserver", "", "authoritative nameserver (host[:port]) for the axfr provider")
+  flag.StringVar(&config.tsig, "tsig", "", "tsig key for zone transfers as [algorithm:]name:secret")
+  flag.Int64Var(&config.pageSize, "page-size", 0, "items requested per page when listing route53 zones and records (service default when 0)")
+  flag.StringVar(&config.ixfrCache, "ixfr-cache", "", "directory caching transferred zones; enables IXFR on repeat runs")
+  flag.StringVar(&publicSuffixFile, "psl", "", "public suffix list file (public_suffix_list.dat) to use instead of the built in copy")
   flag.StringVar(&resourceRecord, "type", "", "resource record; DNS record type")
   flag.Usage = domaniaUsage
   flag.Parse()
 
-  //initialize access to aws api
-  sess := session.Must(session.NewSession())
-  route53svc := route53.New(sess)
+  if *printSchema {
+    fmt.Print(outputSchema)
+    os.Exit(0)
+  }
+
+  if len(*outputFormat) == 0 && !*autoMode {
+    *outputFormat = "table"
+  }
+
+  formatter, formatterErr := NewOutputFormatter(*outputFormat)
+  if formatterErr != nil {
+    fmt.Fprintf(os.Stderr, "[Error] %s\n", formatterErr.Error())
+    os.Exit(1)
+  }
+
+  thresholds, thresholdsErr := NewExpiryThresholds(*warnDays, *critDays)
+  if thresholdsErr != nil {
+    fmt.Fprintf(os.Stderr, "[Error] %s\n", thresholdsErr.Error())
+    os.Exit(1)
+  }
+  siteThresholds = thresholds
+  revocationLookups = !*noRevocation
+
+  //zone names are split up according to the public suffix list
+  if len(publicSuffixFile) > 0 {
+    suffixList, suffixErr := LoadPublicSuffixList(publicSuffixFile)
+    if suffixErr != nil {
+      fmt.Fprintf(os.Stderr, "[Error] %s\n", suffixErr.Error())
+      os.Exit(1)
+    }
+
+    publicSuffixes = suffixList
+  }
+
+  //initialize access to the dns provider's api
+  config.zoneFiles = splitList(zoneFiles)
+  config.zoneNames = splitList(transferZones)
+
+  if len(accountsFile) > 0 {
+    accounts, accountsErr := LoadAwsAccounts(accountsFile)
+    if accountsErr != nil {
+      fmt.Fprintf(os.Stderr, "[Error] %s\n", accountsErr.Error())
+      os.Exit(1)
+    }
+
+    config.accounts = accounts
+  }
+
+  if len(profiles) > 0 || len(roleArns) > 0 {
+    config.accounts = append(config.accounts, AwsAccountsFromLists(splitList(profiles), splitList(roleArns))...)
+  }
+
+  provider, providerErr := NewDnsProvider(config)
+  if providerErr != nil {
+    fmt.Fprintf(os.Stderr, "[Error] %s\n", providerErr.Error())
+    os.Exit(1)
+  }
 
   //control flow for modes (see arguments)
   if !*autoMode {
     //INTERACTIVE MODE
     fmt.Println("program running in interactive mode")
     fmt.Println("fetching domains (hosted zones)...")
-    zones, _ := GetHostedZones(route53svc, &route53.ListHostedZonesInput{})
+    zones, zonesErr := provider.GetZones()
+    HandleProviderError(provider, "fetching zones", zonesErr)
+    HzSort(zones, "subdomain")
     HzSort(zones, "domain")
     HzSort(zones, "tld")
     fmt.Printf("found %d domains:\n", len(zones))
-    fmt.Println("ID\t\tdomain and recordset count")
-    fmt.Println("--------------------------------------------")
-    for _, zone := range zones {
-      fmt.Printf("%s\t%s, %d records\n", zone.id, zone.DomainToString(), zone.recordCount)
-    }
+    HandleOutputError(WriteCollection(os.Stdout, formatter, "zones", ZoneItems(zones)))
 
     //user's resource record query loop
     for moreInput {
@@ -209,7 +250,8 @@ func main() {
       }
 
       //preempt resource recordsets for specified zone
-      zoneRecords, _ := GetRecordsetsForZone(route53svc, domainId)
+      zoneRecords, recordsErr := provider.GetRecordsets(domainId)
+      HandleProviderError(provider, "fetching records", recordsErr)
       //specify resource record type
       fmt.Println("what type of resource record are you looking for?")
       fmt.Printf("choice of: %s\n", strings.Join(zoneRecords.GetDistinctTypes(), ", "))
@@ -221,12 +263,8 @@ func main() {
 
       if len((*zoneRecords)[strings.ToUpper(resourceRecord)]) > 0 {
         fmt.Printf("found %d records:\n", len((*zoneRecords)[strings.ToUpper(resourceRecord)]))
-        for _, record := range (*zoneRecords)[strings.ToUpper(resourceRecord)] {
-          fmt.Printf("%s\n", record.name)
-          for _, value := range record.values {
-            fmt.Printf("\t%s\n", value)
-          }
-        }
+        AttachRecordHealthChecks(provider, domainId, zoneRecords)
+        HandleOutputError(WriteCollection(os.Stdout, formatter, "zoneRecords", zoneRecords.RecordItems(resourceRecord)))
       } else {
         fmt.Printf("no records found\n")
       }
@@ -240,36 +278,161 @@ func main() {
     }
   } else {
     //AUTOMATABLE MODE
-    if *checkDomainContent {
+    var history *historyStore
+    runAt := time.Now().UTC()
+    if len(*historyFile) > 0 {
+      var historyErr error
+      history, historyErr = OpenHistoryStore(*historyFile)
+      if historyErr != nil {
+        fmt.Fprintf(os.Stderr, "[Error] %s\n", historyErr.Error())
+        os.Exit(1)
+      }
+      defer history.Close()
+      if len(*historyQuery) == 0 {
+        HandleHistoryError(history.SaveRun(runAt, os.Args[1:]))
+      }
+    }
+
+    if len(*historyQuery) > 0 {
+      //--query saved history
+      if history == nil {
+        fmt.Fprintln(os.Stderr, "[Error] -query needs the -history database to search")
+        os.Exit(1)
+      }
+
+      collection, items, queryErr := history.Query(*historyQuery)
+      if queryErr != nil {
+        fmt.Fprintf(os.Stderr, "[Error] %s\n", queryErr.Error())
+        os.Exit(1)
+      }
+      HandleOutputError(WriteCollection(os.Stdout, formatter, collection, items))
+    } else if *checkDomainContent {
       //--domain content checks
       if len(domainId) > 0 {
-        zoneRecords, _ := GetRecordsetsForZone(route53svc, domainId)
-        aRecordsForDomain := (*zoneRecords)["A"]
-        batch := make(chan string, len(aRecordsForDomain))
-        for i:=0; i<len(aRecordsForDomain); i++ {
-          go ChanneledParseSite(aRecordsForDomain[i].name, batch)
+        zoneRecords, recordsErr := provider.GetRecordsets(domainId)
+        HandleProviderError(provider, "fetching records", recordsErr)
+        check := CheckSite
+        if *deepTls {
+          check = ProbingCheck(check)
+        }
+        if history != nil {
+          HandleHistoryError(history.SaveRecords(runAt, domainId, zoneRecords))
+          check = history.RecordingCheck(runAt, check)
+        }
+        var sites []string
+        for _, aRecord := range (*zoneRecords)["A"] {
+          sites = append(sites, aRecord.name)
         }
 
-        fmt.Printf("{\"sites\":[")
-        for j:=0; j<len(aRecordsForDomain); j++ {
-          fmt.Printf("%s", <- batch)
-          if j != len(aRecordsForDomain) - 1 {
-            fmt.Printf(",")
+        //results are written as each check finishes; an interrupt still closes
+        //the output off (use -o ndjson to follow along)
+        interrupt := make(chan os.Signal, 1)
+        signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+        summary, checkErr := CheckSites(os.Stdout, formatter, sites, check, interrupt)
+        HandleOutputError(checkErr)
+        if summary.Interrupted {
+          if history != nil {
+            history.Close()
           }
+          os.Exit(130)
+        }
+
+        //the worst site decides the exit code (0 ok, 1 warning, 2 critical, 3 unknown)
+        if summary.ExitCode != 0 {
+          if history != nil {
+            history.Close()
+          }
+          os.Exit(summary.ExitCode)
         }
-        fmt.Printf("]}")
       } else {
         fmt.Println("insufficient arguments, when performing domain content checks:\n" +
                     "\t-domain argument is required")
       }
+    } else if *checkDelegation {
+      //--delegation consistency (all zones, or the one given with -domain)
+      checker, checkerErr := NewDelegationChecker(*resolver)
+      if checkerErr != nil {
+        fmt.Fprintf(os.Stderr, "[Error] %s\n", checkerErr.Error())
+        os.Exit(1)
+      }
+
+      reports, reportsErr := checker.CheckZones(provider, domainId)
+      HandleProviderError(provider, "fetching zones and records", reportsErr)
+      HandleOutputError(WriteCollection(os.Stdout, formatter, "delegations", DelegationItems(reports)))
+    } else if len(*snapshotFile) > 0 {
+      //--snapshot every zone and record
+      snapshot, snapshotErr := TakeSnapshot(provider, time.Now().UTC())
+      HandleProviderError(provider, "fetching zones and records", snapshotErr)
+      if *snapshotFile == "-" {
+        fmt.Print(snapshot.Serialize())
+      } else if writeErr := ioutil.WriteFile(*snapshotFile, []byte(snapshot.Serialize()), 0644); writeErr != nil {
+        fmt.Fprintf(os.Stderr, "[Error] %s\n", writeErr.Error())
+        os.Exit(1)
+      }
+    } else if len(*diffFiles) > 0 {
+      //--diff two snapshots, or a snapshot and the live zones
+      var snapshots []*snapshotOutput
+      if len(splitList(*diffFiles)) > 2 {
+        fmt.Fprintln(os.Stderr, "[Error] -diff compares at most two snapshots")
+        os.Exit(1)
+      }
+      for _, path := range splitList(*diffFiles) {
+        snapshot, loadErr := LoadSnapshot(path)
+        if loadErr != nil {
+          fmt.Fprintf(os.Stderr, "[Error] %s\n", loadErr.Error())
+          os.Exit(1)
+        }
+        snapshots = append(snapshots, snapshot)
+      }
+      if len(snapshots) == 1 {
+        live, liveErr := TakeSnapshot(provider, time.Now().UTC())
+        HandleProviderError(provider, "fetching zones and records", liveErr)
+        snapshots = append(snapshots, live)
+      }
+
+      HandleOutputError(WriteCollection(os.Stdout, formatter, "changes", RecordChangeItems(DiffSnapshots(snapshots[0], snapshots[1]))))
+    } else if len(*exportFormat) > 0 {
+      //--export zones (the one given with -domain, or all of them)
+      exporter, exporterErr := NewZoneExporter(*exportFormat, provider.Metadata().name)
+      if exporterErr != nil {
+        fmt.Fprintf(os.Stderr, "[Error] %s\n", exporterErr.Error())
+        os.Exit(1)
+      }
+
+      if len(domainId) > 0 {
+        exportZone, zoneErr := FindZone(provider, domainId)
+        HandleProviderError(provider, "fetching zones", zoneErr)
+        zoneRecords, recordsErr := provider.GetRecordsets(domainId)
+        HandleProviderError(provider, "fetching records", recordsErr)
+        HandleOutputError(exporter.Export(os.Stdout, exportZone, zoneRecords))
+      } else {
+        files, exportErr := ExportZoneFiles(provider, exporter, *exportDir)
+        HandleProviderError(provider, "exporting zones", exportErr)
+        for _, file := range files {
+          fmt.Println(file)
+        }
+      }
+    } else if *registrarInventory {
+      //--registrar inventory, cross referenced with the provider's zones
+      domains, domainsErr := GetAccountsRegisteredDomains(RegistrarClients(provider))
+      HandleProviderError(provider, "fetching registered domains", domainsErr)
+      zones, zonesErr := provider.GetZones()
+      HandleProviderError(provider, "fetching zones", zonesErr)
+      CrossReferenceZones(domains, zones)
+      HandleOutputError(WriteCollection(os.Stdout, formatter, "domains", RegisteredDomainItems(domains)))
     } else {
       //--information gathering
       if len(domainId) == 0 {
-        zones, _ := GetHostedZones(route53svc, &route53.ListHostedZonesInput{})
-        fmt.Println(SerializeZones(zones))
+        //zones are written as they arrive, accounts can have a lot of them
+        HandleProviderError(provider, "fetching zones", WriteZones(os.Stdout, provider, formatter))
       } else if len(domainId) > 0 && len(resourceRecord) > 0 {
-        zoneRecords, _ := GetRecordsetsForZone(route53svc, domainId)
-        fmt.Println(zoneRecords.SerializeRecords(resourceRecord))
+        zoneRecords, recordsErr := provider.GetRecordsets(domainId)
+        HandleProviderError(provider, "fetching records", recordsErr)
+        if history != nil {
+          HandleHistoryError(history.SaveRecords(runAt, domainId, zoneRecords))
+        }
+        AttachRecordHealthChecks(provider, domainId, zoneRecords)
+        HandleOutputError(WriteCollection(os.Stdout, formatter, "zoneRecords", zoneRecords.RecordItems(resourceRecord)))
       } else {
         fmt.Println("insufficient arguments, when information gathering:\n" +
                     "\tno additional arguments: outputs hosted zones\n" +
@@ -1,9 +1,11 @@
 package main
 
 import(
+  "fmt"
   "strconv"
   "strings"
 
+  "github.com/aws/aws-sdk-go/aws"
   "github.com/aws/aws-sdk-go/service/route53"
 )
 
@@ -11,31 +13,67 @@ import(
  *  zone container - holds easy to reference data about dns zones
  */
 type zone struct {
+  accountAlias string
+  accountId string
+  comment string
   domain string
   id string
-  recordCount int64
+  private bool
+  //nil for providers that can't count records without listing them
+  recordCount *int64
+  subdomain string
   tld string
 }
 func (z *zone) DomainToString() string {
-  if len(z.tld) > 0 {
-    //only print domain and tld if we have a tld
-    return z.domain + "." + z.tld
+  var nameParts []string
+
+  //only print the parts we have
+  for _, part := range []string{z.subdomain, z.domain, z.tld} {
+    if len(part) > 0 {
+      nameParts = append(nameParts, part)
+    }
   }
 
-  return z.domain
+  return strings.Join(nameParts, ".")
 }
-func (z *zone) Serialize() string {
-  var jsonString strings.Builder
+/*
+ *  the registrable domain; what gets bought from a registrar (eg. example.co.uk)
+ */
+func (z *zone) RegistrableDomain() string {
+  if len(z.domain) == 0 || len(z.tld) == 0 {
+    return z.domain
+  }
 
-  //there's got to be a better way...
-  jsonString.WriteString("{")
-  jsonString.WriteString("\"id\":\"" + z.id + "\",")
-  jsonString.WriteString("\"domain\":\"" + z.domain + "\",")
-  jsonString.WriteString("\"tld\":\"" + z.tld + "\",")
-  jsonString.WriteString("\"recordCount\":" + strconv.FormatInt(z.recordCount, 10))
-  jsonString.WriteString("}")
+  return z.domain + "." + z.tld
+}
+/*
+ *  separate out the subdomain, domain and tld from a zone's name using the public
+ *  suffix list (eg. dev.example.co.uk -> |dev|example|co.uk|); a trailing dot
+ *  (fully qualified) is ignored. The tld is the whole public suffix.
+ */
+func (z *zone) SetName(name string) {
+  name = strings.TrimSuffix(name, ".")
+  suffix := publicSuffixes.PublicSuffix(strings.ToLower(name))
+
+  z.subdomain, z.domain, z.tld = "", "", ""
+  if len(suffix) >= len(name) {
+    //the zone is itself a public suffix, there's nothing registrable about it
+    z.tld = name
+    return
+  }
 
-  return jsonString.String()
+  //keep the name's own case, the suffix only says where to cut
+  z.tld = name[len(name) - len(suffix):]
+  rest := strings.TrimSuffix(name[:len(name) - len(suffix)], ".")
+  if lastDot := strings.LastIndex(rest, "."); lastDot >= 0 {
+    z.subdomain = rest[:lastDot]
+    z.domain = rest[lastDot + 1:]
+  } else {
+    z.domain = rest
+  }
+}
+func (z *zone) Serialize() string {
+  return marshalJson(z.Output())
 }
 
 /*
@@ -43,19 +81,13 @@ func (z *zone) Serialize() string {
  * loosely associated with zone type
  */
 func SerializeZones(zones []*zone) string {
-  var jsonString strings.Builder
+  zoneOutputs := make([]*zoneOutput, 0, len(zones))
 
-  jsonString.WriteString("{\"zones\":[")
-  for i, zone := range zones {
-    jsonString.WriteString(zone.Serialize())
-    if i < len(zones) - 1 {
-      jsonString.WriteString(",")
-    }
+  for _, zone := range zones {
+    zoneOutputs = append(zoneOutputs, zone.Output())
   }
 
-  jsonString.WriteString("]}")
-
-  return jsonString.String()
+  return serializeDocument("zones", zoneOutputs)
 }
 
 
@@ -63,28 +95,100 @@ func SerializeZones(zones []*zone) string {
  *  dns record - resource name/values pair
  *  note: values is an array
  *        there is also a reference back to the zone
+ *        routing policy fields (setIdentifier and friends) are route53 specific;
+ *        they explain why one name can have several records of the same type
+ *        values are kept in presentation format; see ParsedValues() for fields
  */
 type record struct {
   name string
+  evaluateTargetHealth bool
+  failover string
+  geoLocation *geoLocation
+  healthCheck *healthCheckSummary
+  healthCheckId string
   isAlias bool
+  multiValueAnswer bool
+  proxied bool
+  region string
+  rrType string
+  setIdentifier string
+  ttl int64
+  weight *int64
   zoneRef string
   values []string
 }
+
+/*
+ *  geolocation routing - continent, country and (US only) subdivision codes
+ */
+type geoLocation struct {
+  continentCode string
+  countryCode string
+  subdivisionCode string
+}
 func (r *record) Serialize() string {
-  var jsonString strings.Builder
-
-  jsonString.WriteString("{")
-  jsonString.WriteString("\"name\":\"" + r.name + "\",")
-  jsonString.WriteString("\"isAlias\":" + strconv.FormatBool(r.isAlias) + ",")
-  //don't include if the zoneRef field is blank/empty
-  if len(r.zoneRef) > 0 {
-    jsonString.WriteString("\"zoneReference\":\"" + r.zoneRef + "\",")
+  return marshalJson(r.Output())
+}
+
+/*
+ *  Values parsed according to the record's type; alias targets are left raw.
+ */
+func (r *record) ParsedValues() []*recordValue {
+  parsedValues := make([]*recordValue, len(r.values))
+
+  for i, value := range r.values {
+    if r.isAlias {
+      parsedValues[i] = &recordValue{raw: value}
+    } else {
+      parsedValues[i] = ParseRecordValue(r.rrType, value)
+    }
   }
 
-  jsonString.WriteString("\"values\":[\"" + strings.Join(r.values, "\",\"") + "\"]")
-  jsonString.WriteString("}")
+  return parsedValues
+}
 
-  return jsonString.String()
+/*
+ *  Describes the record's routing policy (eg. "weighted: blue, weight 10") or
+ *  returns an empty string for simple routing.
+ */
+func (r *record) RoutingToString() string {
+  var policy string
+
+  switch {
+  case r.weight != nil:
+    policy = "weighted: " + r.setIdentifier + ", weight " + strconv.FormatInt(*r.weight, 10)
+  case len(r.region) > 0:
+    policy = "latency: " + r.setIdentifier + ", region " + r.region
+  case r.geoLocation != nil:
+    location := strings.Trim(r.geoLocation.continentCode + "/" + r.geoLocation.countryCode + "/" + r.geoLocation.subdivisionCode, "/")
+    policy = "geolocation: " + r.setIdentifier + ", location " + location
+  case len(r.failover) > 0:
+    policy = "failover: " + r.setIdentifier + ", " + strings.ToLower(r.failover)
+  case r.multiValueAnswer:
+    policy = "multivalue: " + r.setIdentifier
+  default:
+    return ""
+  }
+
+  if len(r.healthCheckId) > 0 {
+    policy += ", health check " + r.healthCheckId
+    if r.healthCheck != nil {
+      policy += " is " + r.healthCheck.status
+    }
+  }
+
+  return policy
+}
+
+/*
+ *  Wraps text in quotes as a DNS character-string (how TXT values are presented),
+ *  escaping embedded quotes and backslashes.
+ */
+func QuoteCharacterString(text string) string {
+  escaped := strings.Replace(text, "\\", "\\\\", -1)
+  escaped = strings.Replace(escaped, "\"", "\\\"", -1)
+
+  return "\"" + escaped + "\""
 }
 
 
@@ -111,44 +215,84 @@ func (rset *recordset) GetDistinctTypes() []string {
 func (rset *recordset) HashRecordsetTypes(recordsets []*route53.ResourceRecordSet) {
   //for each recordset returned...
   for _, recordset := range recordsets {
-    var recordvals strings.Builder
     currentRecordset := new(record)
 
     //lop off the dot at the end of the recordset name
-    currentRecordset.name = string(*recordset.Name)[:len(*recordset.Name)-1]
+    currentRecordset.name = UnescapeRoute53Name(string(*recordset.Name)[:len(*recordset.Name)-1])
+    currentRecordset.rrType = aws.StringValue(recordset.Type)
     if len(recordset.ResourceRecords) > 0 {
-      //and parse the resource records (values of the recordset) into a []string
-      for j, rval := range recordset.ResourceRecords {
-        recordvals.WriteString(*rval.Value)
-        if j < len(recordset.ResourceRecords) - 1 {
-          recordvals.WriteString(",")
-        }
+      //and parse the resource records (values of the recordset) into a []string;
+      //values are kept whole, TXT data can have commas of its own
+      for _, rval := range recordset.ResourceRecords {
+        currentRecordset.values = append(currentRecordset.values, aws.StringValue(rval.Value))
       }
     } else {
       //handle an alias target
       currentRecordset.isAlias = true
-      recordvals.WriteString(*recordset.AliasTarget.DNSName)
+      currentRecordset.values = []string{*recordset.AliasTarget.DNSName}
       currentRecordset.zoneRef = *recordset.AliasTarget.HostedZoneId
+      currentRecordset.evaluateTargetHealth = aws.BoolValue(recordset.AliasTarget.EvaluateTargetHealth)
+    }
+
+    //ttl and routing policy (weighted, latency, geolocation, failover, multivalue)
+    currentRecordset.ttl = aws.Int64Value(recordset.TTL)
+    currentRecordset.setIdentifier = aws.StringValue(recordset.SetIdentifier)
+    currentRecordset.weight = recordset.Weight
+    currentRecordset.region = aws.StringValue(recordset.Region)
+    currentRecordset.failover = aws.StringValue(recordset.Failover)
+    currentRecordset.multiValueAnswer = aws.BoolValue(recordset.MultiValueAnswer)
+    currentRecordset.healthCheckId = aws.StringValue(recordset.HealthCheckId)
+    if recordset.GeoLocation != nil {
+      currentRecordset.geoLocation = &geoLocation{
+        continentCode: aws.StringValue(recordset.GeoLocation.ContinentCode),
+        countryCode: aws.StringValue(recordset.GeoLocation.CountryCode),
+        subdivisionCode: aws.StringValue(recordset.GeoLocation.SubdivisionCode),
+      }
     }
 
-    currentRecordset.values = strings.Split(recordvals.String(), ",")
     //add recordset to the correct type bucket
     (*rset)[*recordset.Type] = append((*rset)[*recordset.Type], currentRecordset)
   }
 }
-func (rset *recordset) SerializeRecords(recordType string) string {
-  var specificRecords = (*rset)[strings.ToUpper(recordType)]
-  var jsonString strings.Builder
 
-  jsonString.WriteString("{\"zoneRecords\":[")
-  for i, rec := range specificRecords {
-    jsonString.WriteString(rec.Serialize())
-    if i < len(specificRecords) - 1 {
-      jsonString.WriteString(",")
+/*
+ *  Route53 returns some characters in names as octal escapes (*.example.com
+ *  comes back as \052.example.com); printable ones are turned back into
+ *  themselves, anything else is left as a decimal \DDD escape, the form zone
+ *  files use.
+ */
+func UnescapeRoute53Name(name string) string {
+  var unescaped strings.Builder
+
+  for i := 0; i < len(name); i++ {
+    if name[i] != '\\' || i + 3 >= len(name) {
+      unescaped.WriteByte(name[i])
+      continue
+    }
+
+    code, parseErr := strconv.ParseUint(name[i + 1:i + 4], 8, 8)
+    if parseErr != nil {
+      unescaped.WriteByte(name[i])
+      continue
+    }
+
+    if code > ' ' && code <= '~' && code != '.' && code != '\\' {
+      unescaped.WriteByte(byte(code))
+    } else {
+      fmt.Fprintf(&unescaped, "\\%03d", code)
     }
+    i += 3
   }
 
-  jsonString.WriteString("]}")
+  return unescaped.String()
+}
+func (rset *recordset) SerializeRecords(recordType string) string {
+  var specificRecords = (*rset)[strings.ToUpper(recordType)]
+  recordOutputs := make([]*recordOutput, 0, len(specificRecords))
+
+  for _, rec := range specificRecords {
+    recordOutputs = append(recordOutputs, rec.Output())
+  }
 
-  return jsonString.String()
+  return serializeDocument("zoneRecords", recordOutputs)
 }
@@ -2,6 +2,9 @@ package main
 import (
   "encoding/json"
   "testing"
+
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/route53"
 )
 
 /*
@@ -56,6 +59,29 @@ func TestZoneDomainToString(t *testing.T) {
   }
 }
 
+func TestZoneSetName(t *testing.T) {
+  z := new(zone)
+
+  //test cases
+  //  1: multi-label public suffixes stay together
+  //  2: subdomains are kept apart from the registrable domain
+  //  3: a zone that is a public suffix has no registrable domain
+  z.SetName("example.co.uk.")
+  if z.domain != "example" || z.tld != "co.uk" || z.RegistrableDomain() != "example.co.uk" {
+    t.Errorf("tc1 - unexpected split: %+v", z)
+  }
+
+  z.SetName("dev.eu.example.com.au")
+  if z.subdomain != "dev.eu" || z.domain != "example" || z.tld != "com.au" || z.DomainToString() != "dev.eu.example.com.au" {
+    t.Errorf("tc2 - unexpected split: %+v", z)
+  }
+
+  z.SetName("co.uk")
+  if len(z.domain) != 0 || z.tld != "co.uk" || len(z.RegistrableDomain()) != 0 || z.DomainToString() != "co.uk" {
+    t.Errorf("tc3 - unexpected split: %+v", z)
+  }
+}
+
 func TestZoneSerialize(t *testing.T) {
   z := new(zone)
   tc1 := z.Serialize()
@@ -136,3 +162,78 @@ func TestRecordsetSerializeRecords(t *testing.T) {
     t.Errorf("tc2 - expected key, \"zoneRecords\" is missing or has the wrong number of elements: %+v", jsonObject)
   }
 }
+
+func TestHashRecordsetTypesRouting(t *testing.T) {
+  var jsonObject map[string]interface{}
+  zoneRecordset := make(recordset)
+  weighted := func(setIdentifier string, weight int64, value string) *route53.ResourceRecordSet {
+    return &route53.ResourceRecordSet{
+      Name: aws.String("www.example.com."),
+      Type: aws.String("A"),
+      TTL: aws.Int64(60),
+      SetIdentifier: aws.String(setIdentifier),
+      Weight: aws.Int64(weight),
+      HealthCheckId: aws.String("hc-" + setIdentifier),
+      ResourceRecords: []*route53.ResourceRecord{&route53.ResourceRecord{Value: aws.String(value)}},
+    }
+  }
+  alias := &route53.ResourceRecordSet{
+    Name: aws.String("example.com."),
+    Type: aws.String("A"),
+    AliasTarget: &route53.AliasTarget{
+      DNSName: aws.String("dualstack.lb.amazonaws.com."),
+      HostedZoneId: aws.String("Z35SXDOTRQ7X7K"),
+      EvaluateTargetHealth: aws.Bool(true),
+    },
+    Failover: aws.String("PRIMARY"),
+    SetIdentifier: aws.String("primary"),
+  }
+
+  //test cases
+  //  1: ttl, set identifier, weight and health check are kept for each weighted record
+  //  2: a zero weight is still reported
+  //  3: alias records keep evaluateTargetHealth and their failover role
+  //  4: serialized records include the routing fields (and alias records omit ttl)
+  zoneRecordset.HashRecordsetTypes([]*route53.ResourceRecordSet{weighted("blue", 10, "192.0.2.1"), weighted("green", 0, "192.0.2.2"), alias})
+  blue, green, primary := zoneRecordset["A"][0], zoneRecordset["A"][1], zoneRecordset["A"][2]
+  if blue.ttl != 60 || blue.setIdentifier != "blue" || *blue.weight != 10 || blue.healthCheckId != "hc-blue" {
+    t.Errorf("tc1 - unexpected weighted record: %+v", blue)
+  }
+
+  if green.weight == nil || *green.weight != 0 || green.RoutingToString() != "weighted: green, weight 0, health check hc-green" {
+    t.Errorf("tc2 - unexpected zero weight record: %s", green.RoutingToString())
+  }
+
+  if !primary.isAlias || !primary.evaluateTargetHealth || primary.failover != "PRIMARY" {
+    t.Errorf("tc3 - unexpected alias record: %+v", primary)
+  }
+
+  json.Unmarshal([]byte(blue.Serialize()), &jsonObject)
+  if jsonObject["weight"] != float64(10) || jsonObject["setIdentifier"] != "blue" || jsonObject["ttl"] != float64(60) {
+    t.Errorf("tc4 - serialized record is missing routing fields: %s", blue.Serialize())
+  }
+
+  jsonObject = nil
+  json.Unmarshal([]byte(primary.Serialize()), &jsonObject)
+  if _, hasTtl := jsonObject["ttl"]; hasTtl || jsonObject["evaluateTargetHealth"] != true || jsonObject["failover"] != "PRIMARY" {
+    t.Errorf("tc4 - unexpected serialized alias record: %s", primary.Serialize())
+  }
+}
+
+func TestUnescapeRoute53Name(t *testing.T) {
+  //test cases
+  //  1: octal escapes of printable characters become the character
+  //  2: dots, backslashes and unprintable characters stay escaped, in decimal
+  //  3: names without escapes are unchanged
+  if tc1 := UnescapeRoute53Name("\\052.example.com"); tc1 != "*.example.com" {
+    t.Errorf("tc1 - expected *.example.com, received: %s", tc1)
+  }
+
+  if tc2 := UnescapeRoute53Name("a\\056b\\134\\040.example.com"); tc2 != "a\\046b\\092\\032.example.com" {
+    t.Errorf("tc2 - expected a\\046b\\092\\032.example.com, received: %s", tc2)
+  }
+
+  if tc3 := UnescapeRoute53Name("www.example.com"); tc3 != "www.example.com" {
+    t.Errorf("tc3 - expected www.example.com, received: %s", tc3)
+  }
+}
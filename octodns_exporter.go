@@ -0,0 +1,115 @@
+package main
+
+import(
+  "fmt"
+  "io"
+  "strings"
+)
+
+/*
+ *  octodns exporter - a YamlProvider zone file (<zone>.yaml): records keyed by
+ *  their name relative to the zone ("" is the apex), each name holding a list of
+ *  records. Octodns manages the SOA and apex NS itself; those, and Route53
+ *  constructs it has no plain form for (routing policies, aliases away from the
+ *  apex), are written as comments.
+ */
+type octodnsExporter struct {}
+func (e *octodnsExporter) Extension() string {
+  return "yaml"
+}
+func (e *octodnsExporter) Export(w io.Writer, z *zone, zoneRecords *recordset) error {
+  var out, ownerRecords strings.Builder
+  origin := strings.TrimSuffix(z.DomainToString(), ".")
+  owner := ""
+  ownerHasRecords := false
+
+  //a name is only written as a key if it has something besides comments
+  flushOwner := func() {
+    if ownerHasRecords {
+      fmt.Fprintf(&out, "%s:\n", marshalJson(owner))
+    }
+    out.WriteString(ownerRecords.String())
+    ownerRecords.Reset()
+    ownerHasRecords = false
+  }
+
+  fmt.Fprintf(&out, "# %s (zone %s)\n", origin, z.id)
+  out.WriteString("---\n")
+  for _, rec := range SortedRecords(zoneRecords, origin) {
+    recordOwner := strings.ToLower(RelativeOwnerName(rec.name, origin))
+    if recordOwner == "@" {
+      recordOwner = ""
+    }
+    isApex := len(recordOwner) == 0
+    if rec.rrType == "SOA" || (rec.rrType == "NS" && isApex && !rec.isAlias) {
+      continue
+    }
+
+    if recordOwner != owner {
+      flushOwner()
+      owner = recordOwner
+    }
+
+    entry := e.record(rec, isApex)
+    ownerRecords.WriteString(entry)
+    ownerHasRecords = ownerHasRecords || !strings.HasPrefix(entry, "#")
+  }
+  flushOwner()
+
+  _, err := io.WriteString(w, out.String())
+  return err
+}
+
+/*
+ *  one record as a list item (or a comment when octodns can't take it as is)
+ */
+func (e *octodnsExporter) record(rec *record, isApex bool) string {
+  var entry strings.Builder
+
+  if routing := rec.RoutingToString(); len(routing) > 0 {
+    //routing policies would have to be rewritten as octodns dynamic records
+    return "# " + rec.name + " " + rec.rrType + " with a routing policy (" + routing + "): " + strings.Join(rec.values, ", ") + "\n"
+  }
+
+  if rec.isAlias {
+    if !isApex {
+      return fmt.Sprintf("# alias: %s %s -> %s (hosted zone %s)\n", rec.name, rec.rrType, strings.Join(rec.values, " "), rec.zoneRef)
+    }
+
+    fmt.Fprintf(&entry, "- type: ALIAS\n  value: %s\n", marshalJson(AbsoluteRecordValue("CNAME", strings.Join(rec.values, " "))))
+    return entry.String()
+  }
+
+  fmt.Fprintf(&entry, "- type: %s\n  ttl: %d\n", rec.rrType, rec.ttl)
+  switch rec.rrType {
+  case "A", "AAAA", "NS":
+    entry.WriteString("  values:\n")
+    for _, value := range rec.values {
+      fmt.Fprintf(&entry, "  - %s\n", marshalJson(AbsoluteRecordValue(rec.rrType, value)))
+    }
+  case "CNAME", "DNAME", "PTR":
+    fmt.Fprintf(&entry, "  value: %s\n", marshalJson(AbsoluteRecordValue(rec.rrType, strings.Join(rec.values, " "))))
+  case "MX", "SRV", "CAA", "TXT", "SPF":
+    entry.WriteString("  values:\n")
+    for _, value := range rec.values {
+      parsed := ParseRecordValue(rec.rrType, value)
+      switch {
+      case parsed.mx != nil:
+        fmt.Fprintf(&entry, "  - exchange: %s\n    preference: %d\n", marshalJson(AbsoluteRecordValue("CNAME", parsed.mx.exchange)), parsed.mx.preference)
+      case parsed.srv != nil:
+        fmt.Fprintf(&entry, "  - port: %d\n    priority: %d\n    target: %s\n    weight: %d\n", parsed.srv.port, parsed.srv.priority, marshalJson(AbsoluteRecordValue("CNAME", parsed.srv.target)), parsed.srv.weight)
+      case parsed.caa != nil:
+        fmt.Fprintf(&entry, "  - flags: %d\n    tag: %s\n    value: %s\n", parsed.caa.flag, marshalJson(parsed.caa.tag), marshalJson(parsed.caa.value))
+      case parsed.characterStrings != nil:
+        //octodns joins (and re-splits) long strings itself, but wants ; escaped
+        fmt.Fprintf(&entry, "  - %s\n", marshalJson(strings.Replace(parsed.Text(), ";", "\\;", -1)))
+      default:
+        return "# " + rec.name + " " + rec.rrType + " value octodns wouldn't read: " + value + "\n"
+      }
+    }
+  default:
+    return "# " + rec.name + " " + rec.rrType + " isn't exported: " + strings.Join(rec.values, ", ") + "\n"
+  }
+
+  return entry.String()
+}
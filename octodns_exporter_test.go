@@ -0,0 +1,41 @@
+package main
+import (
+  "strings"
+  "testing"
+
+  "gopkg.in/yaml.v2"
+)
+
+func TestOctodnsExporterExport(t *testing.T) {
+  var output strings.Builder
+  var document map[string][]map[string]interface{}
+  z, zoneRecords := createExportZone()
+  (*zoneRecords)["A"] = append((*zoneRecords)["A"], &record{name: "blog.example.com", isAlias: true, zoneRef: "Z2FDTNDATAQYW2", values: []string{"d111111abcdef8.cloudfront.net."}})
+
+  //test cases
+  //  1: the export is yaml octodns can read, keyed by relative name ("" for the apex)
+  //  2: SOA and apex NS are left to octodns, delegations are kept
+  //  3: MX, CAA and TXT values are broken into fields; TXT strings are joined with ; escaped
+  //  4: an apex alias is an ALIAS record, others and routing policies are comments
+  err := new(octodnsExporter).Export(&output, z, zoneRecords)
+  if err != nil || yaml.Unmarshal([]byte(output.String()), &document) != nil || document[""] == nil || document["www"] != nil {
+    t.Fatalf("tc1 - unexpected export: %s (error: %v)", output.String(), err)
+  }
+
+  for _, entry := range document[""] {
+    if entry["type"] == "SOA" || entry["type"] == "NS" {
+      t.Errorf("tc2 - expected %s to be left out of the apex", entry["type"])
+    }
+  }
+  if len(document["dev"]) != 1 || document["dev"][0]["type"] != "NS" {
+    t.Errorf("tc2 - expected the dev delegation: %+v", document["dev"])
+  }
+
+  if !strings.Contains(output.String(), "  - exchange: \"mail.example.com.\"\n    preference: 10\n") || !strings.Contains(output.String(), "  - flags: 0\n    tag: \"issue\"\n    value: \"letsencrypt.org; validationmethods=dns-01\"\n") || !strings.Contains(output.String(), "  - \"v=spf1 a mx\\\\; ~allsay \\\"hi\\\", \\\\ \\ttab\"\n") {
+    t.Errorf("tc3 - unexpected values: %s", output.String())
+  }
+
+  if document[""][0]["type"] != "ALIAS" || document[""][0]["value"] != "dualstack.lb.amazonaws.com." || !strings.Contains(output.String(), "# alias: blog.example.com A -> d111111abcdef8.cloudfront.net. (hosted zone Z2FDTNDATAQYW2)\n") || !strings.Contains(output.String(), "# www.example.com A with a routing policy (weighted: blue, weight 10): 192.0.2.1\n") {
+    t.Errorf("tc4 - unexpected aliases and routing policies: %s", output.String())
+  }
+}
@@ -0,0 +1,463 @@
+package main
+
+import(
+  "bytes"
+  "encoding/csv"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "strconv"
+  "strings"
+  "text/tabwriter"
+  "time"
+
+  "gopkg.in/yaml.v2"
+)
+
+/*
+ *  output formatter - renders a collection (zones, zoneRecords, sites, domains or
+ *  delegations) of output model items; items are written one at a time so a
+ *  format that can stream, does
+ */
+type outputFormatter interface {
+  Begin(w io.Writer, collection string) error
+  WriteItem(item interface{}) error
+  End() error
+}
+
+/*
+ *  formatters that can close a collection with a summary (counts, timings);
+ *  others just leave it out
+ */
+type summaryFormatter interface {
+  WriteSummary(summary interface{}) error
+}
+
+/*
+ *  output model types that can be laid out in rows and columns (table and csv);
+ *  a record has a row per value
+ */
+type tabularOutput interface {
+  Columns() []string
+  Rows() [][]string
+}
+
+/*
+ *  Picks the formatter for an -o argument.
+ */
+func NewOutputFormatter(format string) (outputFormatter, error) {
+  switch strings.ToLower(format) {
+  case "", "json":
+    return new(jsonFormatter), nil
+  case "ndjson":
+    return new(ndjsonFormatter), nil
+  case "table":
+    return new(tableFormatter), nil
+  case "csv":
+    return new(csvFormatter), nil
+  case "yaml":
+    return new(yamlFormatter), nil
+  }
+
+  return nil, fmt.Errorf("unknown output format \"%s\" (json, ndjson, table, csv or yaml)", format)
+}
+
+/*
+ *  Writes a whole collection with the formatter.
+ */
+func WriteCollection(w io.Writer, formatter outputFormatter, collection string, items []interface{}) error {
+  if err := formatter.Begin(w, collection); err != nil {
+    return err
+  }
+
+  for _, item := range items {
+    if err := formatter.WriteItem(item); err != nil {
+      return err
+    }
+  }
+
+  return formatter.End()
+}
+
+/*
+ *  output can't be written (a closed pipe, usually); nothing left to do
+ */
+func HandleOutputError(err error) {
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "[Error] writing output...\n%s\n\n", err.Error())
+    os.Exit(1)
+  }
+}
+
+/*
+ *  json - the versioned document (see outputSchema), written as items arrive
+ */
+type jsonFormatter struct {
+  count int
+  summary interface{}
+  w io.Writer
+}
+func (f *jsonFormatter) Begin(w io.Writer, collection string) error {
+  f.count, f.summary, f.w = 0, nil, w
+  _, err := io.WriteString(w, "{\"schemaVersion\":\"" + outputSchemaVersion + "\",\"" + collection + "\":[")
+
+  return err
+}
+func (f *jsonFormatter) WriteItem(item interface{}) error {
+  separator := ""
+  if f.count > 0 {
+    separator = ","
+  }
+
+  f.count++
+  _, err := io.WriteString(f.w, separator + marshalJson(item))
+  return err
+}
+func (f *jsonFormatter) WriteSummary(summary interface{}) error {
+  f.summary = summary
+
+  return nil
+}
+func (f *jsonFormatter) End() error {
+  closing := "]}\n"
+  if f.summary != nil {
+    closing = "],\"summary\":" + marshalJson(f.summary) + "}\n"
+  }
+
+  _, err := io.WriteString(f.w, closing)
+  return err
+}
+
+/*
+ *  ndjson - an item per line, nothing else; suits streaming into log pipelines
+ */
+type ndjsonFormatter struct {
+  w io.Writer
+}
+func (f *ndjsonFormatter) Begin(w io.Writer, collection string) error {
+  f.w = w
+
+  return nil
+}
+func (f *ndjsonFormatter) WriteItem(item interface{}) error {
+  _, err := io.WriteString(f.w, marshalJson(item) + "\n")
+
+  return err
+}
+func (f *ndjsonFormatter) WriteSummary(summary interface{}) error {
+  _, err := io.WriteString(f.w, "{\"summary\":" + marshalJson(summary) + "}\n")
+
+  return err
+}
+func (f *ndjsonFormatter) End() error {
+  return nil
+}
+
+/*
+ *  table - aligned columns for people; the header comes from the first item
+ */
+type tableFormatter struct {
+  count int
+  tw *tabwriter.Writer
+}
+func (f *tableFormatter) Begin(w io.Writer, collection string) error {
+  f.count = 0
+  f.tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+  return nil
+}
+func (f *tableFormatter) WriteItem(item interface{}) error {
+  tabular, isTabular := item.(tabularOutput)
+  if !isTabular {
+    return fmt.Errorf("%T can't be shown as a table", item)
+  }
+
+  if f.count == 0 {
+    fmt.Fprintln(f.tw, strings.Join(tabular.Columns(), "\t"))
+  }
+
+  f.count++
+  for _, row := range tabular.Rows() {
+    if _, err := fmt.Fprintln(f.tw, strings.Join(row, "\t")); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+func (f *tableFormatter) End() error {
+  if f.count == 0 {
+    fmt.Fprintln(f.tw, "(none)")
+  }
+
+  return f.tw.Flush()
+}
+
+/*
+ *  csv - a header row then a row per item (per value for records)
+ */
+type csvFormatter struct {
+  count int
+  cw *csv.Writer
+}
+func (f *csvFormatter) Begin(w io.Writer, collection string) error {
+  f.count = 0
+  f.cw = csv.NewWriter(w)
+
+  return nil
+}
+func (f *csvFormatter) WriteItem(item interface{}) error {
+  tabular, isTabular := item.(tabularOutput)
+  if !isTabular {
+    return fmt.Errorf("%T can't be written as csv", item)
+  }
+
+  if f.count == 0 {
+    f.cw.Write(tabular.Columns())
+  }
+
+  f.count++
+  return f.cw.WriteAll(tabular.Rows())
+}
+func (f *csvFormatter) End() error {
+  f.cw.Flush()
+
+  return f.cw.Error()
+}
+
+/*
+ *  yaml - the same document as json; yaml can't be closed off item by item, so
+ *  items are held until the end
+ */
+type yamlFormatter struct {
+  collection string
+  items []interface{}
+  summary interface{}
+  w io.Writer
+}
+func (f *yamlFormatter) Begin(w io.Writer, collection string) error {
+  f.collection, f.items, f.summary, f.w = collection, []interface{}{}, nil, w
+
+  return nil
+}
+func (f *yamlFormatter) WriteItem(item interface{}) error {
+  ordered, err := orderedYaml(item)
+  if err != nil {
+    return err
+  }
+
+  f.items = append(f.items, ordered)
+  return nil
+}
+func (f *yamlFormatter) WriteSummary(summary interface{}) error {
+  ordered, err := orderedYaml(summary)
+  f.summary = ordered
+
+  return err
+}
+func (f *yamlFormatter) End() error {
+  document := yaml.MapSlice{
+    {Key: "schemaVersion", Value: outputSchemaVersion},
+    {Key: f.collection, Value: f.items},
+  }
+  if f.summary != nil {
+    document = append(document, yaml.MapItem{Key: "summary", Value: f.summary})
+  }
+
+  out, err := yaml.Marshal(document)
+  if err != nil {
+    return err
+  }
+
+  _, err = f.w.Write(out)
+  return err
+}
+
+/*
+ *  Converts an output item to yaml friendly values by way of its json form, so
+ *  the yaml uses the same field names (and order) as the json.
+ */
+func orderedYaml(item interface{}) (interface{}, error) {
+  decoder := json.NewDecoder(bytes.NewReader([]byte(marshalJson(item))))
+  decoder.UseNumber()
+
+  return decodeOrdered(decoder)
+}
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+  token, err := decoder.Token()
+  if err != nil {
+    return nil, err
+  }
+
+  switch value := token.(type) {
+  case json.Delim:
+    if value == '{' {
+      object := yaml.MapSlice{}
+      for decoder.More() {
+        key, _ := decoder.Token()
+        member, err := decodeOrdered(decoder)
+        if err != nil {
+          return nil, err
+        }
+        object = append(object, yaml.MapItem{Key: key, Value: member})
+      }
+      decoder.Token()
+      return object, nil
+    }
+    if value == '[' {
+      array := []interface{}{}
+      for decoder.More() {
+        element, err := decodeOrdered(decoder)
+        if err != nil {
+          return nil, err
+        }
+        array = append(array, element)
+      }
+      decoder.Token()
+      return array, nil
+    }
+    return nil, errors.New("unexpected delimiter in output")
+  case json.Number:
+    if integer, err := value.Int64(); err == nil {
+      return integer, nil
+    }
+    return value.Float64()
+  }
+
+  return token, nil
+}
+
+/*
+ *  output items for each collection, ready for WriteCollection
+ */
+func ZoneItems(zones []*zone) []interface{} {
+  items := make([]interface{}, len(zones))
+
+  for i, z := range zones {
+    items[i] = z.Output()
+  }
+
+  return items
+}
+func (rset *recordset) RecordItems(recordType string) []interface{} {
+  specificRecords := (*rset)[strings.ToUpper(recordType)]
+  items := make([]interface{}, len(specificRecords))
+
+  for i, rec := range specificRecords {
+    items[i] = rec.Output()
+  }
+
+  return items
+}
+func RegisteredDomainItems(domains []*registeredDomain) []interface{} {
+  items := make([]interface{}, len(domains))
+
+  for i, domain := range domains {
+    items[i] = domain.Output()
+  }
+
+  return items
+}
+func DelegationItems(reports []*delegationReport) []interface{} {
+  items := make([]interface{}, len(reports))
+
+  for i, report := range reports {
+    items[i] = report.Output()
+  }
+
+  return items
+}
+
+func (z *zoneOutput) Columns() []string {
+  return []string{"id", "name", "registrableDomain", "publicSuffix", "recordCount", "accountId", "accountAlias"}
+}
+func (z *zoneOutput) Rows() [][]string {
+  var nameParts []string
+
+  for _, part := range []string{z.Subdomain, z.Domain, z.Tld} {
+    if len(part) > 0 {
+      nameParts = append(nameParts, part)
+    }
+  }
+
+  recordCount := ""
+  if z.RecordCount != nil {
+    recordCount = strconv.FormatInt(*z.RecordCount, 10)
+  }
+
+  return [][]string{{z.Id, strings.Join(nameParts, "."), z.RegistrableDomain, z.PublicSuffix, recordCount, z.AccountId, z.AccountAlias}}
+}
+func (r *recordOutput) Columns() []string {
+  return []string{"name", "type", "ttl", "alias", "setIdentifier", "routing", "healthCheck", "value"}
+}
+func (r *recordOutput) Rows() [][]string {
+  var rows [][]string
+  var routing []string
+  ttl, healthCheck := "", r.HealthCheckId
+
+  if r.Ttl != nil {
+    ttl = strconv.FormatInt(*r.Ttl, 10)
+  }
+  if r.Weight != nil {
+    routing = append(routing, "weight " + strconv.FormatInt(*r.Weight, 10))
+  }
+  if len(r.Region) > 0 {
+    routing = append(routing, "region " + r.Region)
+  }
+  if r.GeoLocation != nil {
+    routing = append(routing, "location " + strings.Trim(r.GeoLocation.ContinentCode + "/" + r.GeoLocation.CountryCode + "/" + r.GeoLocation.SubdivisionCode, "/"))
+  }
+  if len(r.Failover) > 0 {
+    routing = append(routing, strings.ToLower(r.Failover))
+  }
+  if r.MultiValueAnswer {
+    routing = append(routing, "multivalue")
+  }
+  if r.HealthCheck != nil {
+    healthCheck += " (" + r.HealthCheck.Status + ")"
+  }
+
+  for _, value := range r.Values {
+    rows = append(rows, []string{r.Name, r.Type, ttl, strconv.FormatBool(r.IsAlias), r.SetIdentifier, strings.Join(routing, ", "), healthCheck, value})
+  }
+
+  return rows
+}
+func (s *siteOutput) Columns() []string {
+  return []string{"site", "status", "redirectsToHttps", "tlsVersion", "cipherSuite", "certSubject", "certIssuer", "certExpiration", "daysRemaining", "expiryStatus", "siteStatus", "verification", "revocation", "chainIssues", "tlsFindings", "error"}
+}
+func (s *siteOutput) Rows() [][]string {
+  row := []string{s.Site, strconv.Itoa(s.Status), strconv.FormatBool(s.RedirectsToHttps), s.TlsVersion, s.CipherSuite, "", "", "", "", s.ExpiryStatus, s.SiteStatus, "", "", "", "", s.ErrorMessage}
+
+  if s.Cert != nil {
+    row[5], row[6], row[7], row[8] = s.Cert.Subject, s.Cert.Issuer, s.Cert.Expiration.Format(time.RFC3339), strconv.Itoa(s.Cert.DaysRemaining)
+  }
+  if s.Verification != nil {
+    row[11] = VerificationSummary(s.Verification)
+  }
+  if s.Revocation != nil {
+    row[12] = s.Revocation.Status + " (" + s.Revocation.Source + ")"
+  }
+  if s.Chain != nil {
+    row[13] = strings.Join(s.Chain.Issues, "; ")
+  }
+  if s.TlsProbe != nil {
+    row[14] = strings.Join(s.TlsProbe.Findings, "; ")
+  }
+
+  return [][]string{row}
+}
+func (d *registeredDomainOutput) Columns() []string {
+  return []string{"name", "expiration", "daysUntilExpiration", "autoRenew", "transferLock", "nameservers", "hostedZoneIds"}
+}
+func (d *registeredDomainOutput) Rows() [][]string {
+  return [][]string{{d.Name, d.Expiration.Format(time.RFC3339), strconv.Itoa(d.DaysUntilExpiration), strconv.FormatBool(d.AutoRenew), strconv.FormatBool(d.TransferLock), strings.Join(d.Nameservers, " "), strings.Join(d.HostedZoneIds, " ")}}
+}
+func (report *delegationOutput) Columns() []string {
+  return []string{"zoneId", "zoneName", "status", "missing", "extra", "duplicateZoneIds", "error"}
+}
+func (report *delegationOutput) Rows() [][]string {
+  return [][]string{{report.ZoneId, report.ZoneName, report.Status, strings.Join(report.Missing, " "), strings.Join(report.Extra, " "), strings.Join(report.DuplicateZoneIds, " "), report.ErrorMessage}}
+}
@@ -0,0 +1,74 @@
+package main
+import (
+  "encoding/csv"
+  "encoding/json"
+  "strings"
+  "testing"
+
+  "gopkg.in/yaml.v2"
+)
+
+/*
+ *  helper that renders a recordset's TXT records in the given format
+ */
+func formatRecords(t *testing.T, format string) string {
+  var output strings.Builder
+  zoneRecordset := recordset{
+    "TXT": []*record{
+      &record{name: "example.com", rrType: "TXT", ttl: 300, values: []string{"\"v=spf1 a, mx ~all\"", "\"verify=abc\""}},
+      &record{name: "_dmarc.example.com", rrType: "TXT", ttl: 3600, values: []string{"\"v=DMARC1; p=none\""}},
+    },
+  }
+
+  formatter, err := NewOutputFormatter(format)
+  if err != nil {
+    t.Fatalf("unexpected error for %s: %s", format, err.Error())
+  }
+
+  if err = WriteCollection(&output, formatter, "zoneRecords", zoneRecordset.RecordItems("txt")); err != nil {
+    t.Fatalf("unexpected error writing %s: %s", format, err.Error())
+  }
+
+  return output.String()
+}
+
+func TestOutputFormats(t *testing.T) {
+  var jsonObject map[string][]map[string]interface{}
+  var yamlObject map[string]interface{}
+
+  //test cases
+  //  1: json is the versioned document
+  //  2: ndjson is one record per line
+  //  3: csv has a header and a row per record value, commas quoted
+  //  4: table columns line up
+  //  5: yaml decodes to the same document
+  //  6: unknown formats are an error
+  tc1 := formatRecords(t, "json")
+  json.Unmarshal([]byte(tc1), &jsonObject)
+  if len(jsonObject["zoneRecords"]) != 2 || !strings.Contains(tc1, "\"schemaVersion\":\"" + outputSchemaVersion + "\"") {
+    t.Errorf("tc1 - unexpected json: %s", tc1)
+  }
+
+  tc2 := strings.Split(strings.TrimSpace(formatRecords(t, "ndjson")), "\n")
+  if len(tc2) != 2 || !json.Valid([]byte(tc2[0])) || !json.Valid([]byte(tc2[1])) {
+    t.Errorf("tc2 - unexpected ndjson: %q", tc2)
+  }
+
+  tc3, err := csv.NewReader(strings.NewReader(formatRecords(t, "csv"))).ReadAll()
+  if err != nil || len(tc3) != 4 || tc3[0][0] != "name" || tc3[1][len(tc3[1]) - 1] != "\"v=spf1 a, mx ~all\"" || tc3[3][2] != "3600" {
+    t.Errorf("tc3 - unexpected csv: %q (error: %v)", tc3, err)
+  }
+
+  tc4 := strings.Split(strings.TrimSpace(formatRecords(t, "table")), "\n")
+  if len(tc4) != 4 || strings.Index(tc4[0], "type") != strings.Index(tc4[3], "TXT") {
+    t.Errorf("tc4 - unexpected table:\n%s", strings.Join(tc4, "\n"))
+  }
+
+  if err = yaml.Unmarshal([]byte(formatRecords(t, "yaml")), &yamlObject); err != nil || yamlObject["schemaVersion"] != outputSchemaVersion || len(yamlObject["zoneRecords"].([]interface{})) != 2 {
+    t.Errorf("tc5 - unexpected yaml: %+v (error: %v)", yamlObject, err)
+  }
+
+  if _, err = NewOutputFormatter("xml"); err == nil {
+    t.Errorf("tc6 - expected an error for an unknown format")
+  }
+}
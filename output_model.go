@@ -0,0 +1,468 @@
+package main
+
+import(
+  "bytes"
+  "encoding/json"
+  "strings"
+  "time"
+)
+
+//version of the documents printed in automatable mode (see outputSchema); bump
+//the major version when a field is removed or changes meaning
+const outputSchemaVersion string = "2.0"
+
+/*
+ *  output model - what domania prints, kept apart from the types used to gather
+ *  it; field names and json tags here are the published format
+ */
+type zoneOutput struct {
+  Id string `json:"id"`
+  Domain string `json:"domain"`
+  Tld string `json:"tld"`
+  Subdomain string `json:"subdomain"`
+  RegistrableDomain string `json:"registrableDomain"`
+  PublicSuffix string `json:"publicSuffix"`
+  AccountId string `json:"accountId,omitempty"`
+  AccountAlias string `json:"accountAlias,omitempty"`
+  RecordCount *int64 `json:"recordCount,omitempty"`
+}
+type recordOutput struct {
+  Name string `json:"name"`
+  Type string `json:"type,omitempty"`
+  IsAlias bool `json:"isAlias"`
+  ZoneReference string `json:"zoneReference,omitempty"`
+  Proxied bool `json:"proxied,omitempty"`
+  EvaluateTargetHealth *bool `json:"evaluateTargetHealth,omitempty"`
+  Ttl *int64 `json:"ttl,omitempty"`
+  SetIdentifier string `json:"setIdentifier,omitempty"`
+  Weight *int64 `json:"weight,omitempty"`
+  Region string `json:"region,omitempty"`
+  GeoLocation *geoLocationOutput `json:"geoLocation,omitempty"`
+  Failover string `json:"failover,omitempty"`
+  MultiValueAnswer bool `json:"multiValueAnswer,omitempty"`
+  HealthCheckId string `json:"healthCheckId,omitempty"`
+  HealthCheck *healthCheckOutput `json:"healthCheck,omitempty"`
+  Values []string `json:"values"`
+  ParsedValues []*recordValueOutput `json:"parsedValues,omitempty"`
+}
+type geoLocationOutput struct {
+  ContinentCode string `json:"continentCode"`
+  CountryCode string `json:"countryCode"`
+  SubdivisionCode string `json:"subdivisionCode"`
+}
+
+/*
+ *  a parsed value is flat; only the fields of the record's type appear
+ */
+type recordValueOutput struct {
+  Raw string `json:"raw"`
+  *mxValueOutput
+  *srvValueOutput
+  *caaValueOutput
+  *txtValueOutput
+}
+type mxValueOutput struct {
+  Preference int `json:"preference"`
+  Exchange string `json:"exchange"`
+}
+type srvValueOutput struct {
+  Priority int `json:"priority"`
+  Weight int `json:"weight"`
+  Port int `json:"port"`
+  Target string `json:"target"`
+}
+type caaValueOutput struct {
+  Flag int `json:"flag"`
+  Tag string `json:"tag"`
+  Value string `json:"value"`
+}
+type txtValueOutput struct {
+  Strings []string `json:"strings"`
+  Text string `json:"text"`
+}
+type healthCheckOutput struct {
+  Id string `json:"id"`
+  Type string `json:"type"`
+  Target string `json:"target"`
+  Inverted bool `json:"inverted"`
+  Status string `json:"status"`
+  HealthyCheckers int `json:"healthyCheckers"`
+  Checkers []*healthCheckerOutput `json:"checkers"`
+}
+type healthCheckerOutput struct {
+  Region string `json:"region"`
+  IpAddress string `json:"ipAddress"`
+  Healthy bool `json:"healthy"`
+  CheckedAt time.Time `json:"checkedAt"`
+  Report string `json:"report"`
+}
+type siteOutput struct {
+  Site string `json:"site"`
+  Status int `json:"status"`
+  RedirectsToHttps bool `json:"redirectsToHttps"`
+  ExpiryStatus string `json:"expiryStatus"`
+  SiteStatus string `json:"siteStatus"`
+  CipherSuite string `json:"cipherSuite,omitempty"`
+  TlsVersion string `json:"tlsVersion,omitempty"`
+  Cert *certOutput `json:"cert,omitempty"`
+  Chain *chainOutput `json:"chain,omitempty"`
+  Hostname *hostnameOutput `json:"hostname,omitempty"`
+  Verification *verificationOutput `json:"verification,omitempty"`
+  Revocation *revocationOutput `json:"revocation,omitempty"`
+  TlsProbe *tlsProbeOutput `json:"tlsProbe,omitempty"`
+  Error bool `json:"error"`
+  ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+/*
+ *  closes out a run of site checks; counts cover the sites that finished
+ */
+type siteSummaryOutput struct {
+  Sites int `json:"sites"`
+  Completed int `json:"completed"`
+  Errors int `json:"errors"`
+  Encrypted int `json:"encrypted"`
+  RedirectsToHttps int `json:"redirectsToHttps"`
+  Interrupted bool `json:"interrupted"`
+  StartedAt time.Time `json:"startedAt"`
+  DurationMs int64 `json:"durationMs"`
+  WarningDays int `json:"warningDays"`
+  CriticalDays int `json:"criticalDays"`
+  Status string `json:"status"`
+  ExitCode int `json:"exitCode"`
+}
+type certOutput struct {
+  Issuer string `json:"issuer"`
+  Subject string `json:"subject"`
+  Expiration time.Time `json:"expiration"`
+  DaysRemaining int `json:"daysRemaining"`
+  Fingerprint string `json:"fingerprint"`
+}
+
+/*
+ *  the chain as presented by the server (leaf first) and as verified
+ */
+type chainOutput struct {
+  Presented []*certDetailOutput `json:"presented"`
+  Verified bool `json:"verified"`
+  VerifiedChains [][]*certDetailOutput `json:"verifiedChains"`
+  Issues []string `json:"issues"`
+}
+
+/*
+ *  whether the leaf certificate covers the hostname probed, and through which
+ *  of its names
+ */
+type hostnameOutput struct {
+  Hostname string `json:"hostname"`
+  Covered bool `json:"covered"`
+  MatchedNames []string `json:"matchedNames"`
+  MatchedWildcards []string `json:"matchedWildcards"`
+  SubjectAltNames []string `json:"subjectAltNames"`
+}
+
+/*
+ *  verified is only true when nothing failed and the certificate was checked;
+ *  insecureFallback means the site was only reached by skipping verification
+ */
+type verificationOutput struct {
+  Verified bool `json:"verified"`
+  InsecureFallback bool `json:"insecureFallback"`
+  Failures []string `json:"failures"`
+  ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+/*
+ *  revocation status of the leaf and where it came from (stapled, ocsp, crl or
+ *  none); stapled says whether the server staples OCSP responses at all
+ */
+type revocationOutput struct {
+  Status string `json:"status"`
+  Source string `json:"source"`
+  Stapled bool `json:"stapled"`
+  Url string `json:"url,omitempty"`
+  ProducedAt *time.Time `json:"producedAt,omitempty"`
+  ThisUpdate *time.Time `json:"thisUpdate,omitempty"`
+  NextUpdate *time.Time `json:"nextUpdate,omitempty"`
+  RevokedAt *time.Time `json:"revokedAt,omitempty"`
+  Reason string `json:"reason,omitempty"`
+  ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+/*
+ *  every protocol version and cipher suite a server accepted (-deep-tls)
+ */
+type tlsProbeOutput struct {
+  Versions []*tlsVersionOutput `json:"versions"`
+  CipherSuites []*tlsSuiteOutput `json:"cipherSuites"`
+  Findings []string `json:"findings"`
+}
+type tlsVersionOutput struct {
+  Version string `json:"version"`
+  Supported bool `json:"supported"`
+  Deprecated bool `json:"deprecated"`
+}
+type tlsSuiteOutput struct {
+  Version string `json:"version"`
+  Suite string `json:"suite"`
+  Weak bool `json:"weak"`
+  Weakness string `json:"weakness,omitempty"`
+}
+type certDetailOutput struct {
+  Subject string `json:"subject"`
+  Issuer string `json:"issuer"`
+  SerialNumber string `json:"serialNumber"`
+  NotBefore time.Time `json:"notBefore"`
+  NotAfter time.Time `json:"notAfter"`
+  KeyType string `json:"keyType"`
+  KeySize int `json:"keySize"`
+  SignatureAlgorithm string `json:"signatureAlgorithm"`
+  IsCA bool `json:"isCA"`
+  Sha256Fingerprint string `json:"sha256Fingerprint"`
+}
+type registeredDomainOutput struct {
+  Name string `json:"name"`
+  Expiration time.Time `json:"expiration"`
+  DaysUntilExpiration int `json:"daysUntilExpiration"`
+  AutoRenew bool `json:"autoRenew"`
+  TransferLock bool `json:"transferLock"`
+  PrivacyProtection privacyProtectionOutput `json:"privacyProtection"`
+  Nameservers []string `json:"nameservers"`
+  HostedZoneIds []string `json:"hostedZoneIds"`
+}
+type privacyProtectionOutput struct {
+  Admin bool `json:"admin"`
+  Registrant bool `json:"registrant"`
+  Tech bool `json:"tech"`
+}
+type delegationOutput struct {
+  ZoneId string `json:"zoneId"`
+  ZoneName string `json:"zoneName"`
+  Status string `json:"status"`
+  HostedNameservers []string `json:"hostedNameservers"`
+  DelegatedNameservers []string `json:"delegatedNameservers"`
+  Missing []string `json:"missing"`
+  Extra []string `json:"extra"`
+  DuplicateZoneIds []string `json:"duplicateZoneIds"`
+  Error bool `json:"error"`
+  ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+func (z *zone) Output() *zoneOutput {
+  return &zoneOutput{
+    AccountAlias: z.accountAlias,
+    AccountId: z.accountId,
+    Domain: z.domain,
+    Id: z.id,
+    PublicSuffix: z.tld,
+    RecordCount: z.recordCount,
+    RegistrableDomain: z.RegistrableDomain(),
+    Subdomain: z.subdomain,
+    Tld: z.tld,
+  }
+}
+func (r *record) Output() *recordOutput {
+  output := &recordOutput{
+    Failover: r.failover,
+    HealthCheckId: r.healthCheckId,
+    IsAlias: r.isAlias,
+    MultiValueAnswer: r.multiValueAnswer,
+    Name: r.name,
+    Proxied: r.proxied,
+    Region: r.region,
+    SetIdentifier: r.setIdentifier,
+    Type: r.rrType,
+    Values: nonNilStrings(r.values),
+    Weight: r.weight,
+    ZoneReference: r.zoneRef,
+  }
+
+  //alias records take the target's ttl and may evaluate the target's health
+  if r.isAlias {
+    evaluateTargetHealth := r.evaluateTargetHealth
+    output.EvaluateTargetHealth = &evaluateTargetHealth
+  } else {
+    ttl := r.ttl
+    output.Ttl = &ttl
+  }
+  if r.geoLocation != nil {
+    output.GeoLocation = &geoLocationOutput{
+      ContinentCode: r.geoLocation.continentCode,
+      CountryCode: r.geoLocation.countryCode,
+      SubdivisionCode: r.geoLocation.subdivisionCode,
+    }
+  }
+  if r.healthCheck != nil {
+    output.HealthCheck = r.healthCheck.Output()
+  }
+
+  //structured types (MX, SRV, CAA, TXT) also get their values broken down
+  parsedValues := r.ParsedValues()
+  structured := false
+  for _, value := range parsedValues {
+    structured = structured || value.IsStructured()
+  }
+  if structured {
+    for _, value := range parsedValues {
+      output.ParsedValues = append(output.ParsedValues, value.Output())
+    }
+  }
+
+  return output
+}
+func (value *recordValue) Output() *recordValueOutput {
+  output := &recordValueOutput{Raw: value.raw}
+
+  switch {
+  case value.mx != nil:
+    output.mxValueOutput = &mxValueOutput{Exchange: value.mx.exchange, Preference: value.mx.preference}
+  case value.srv != nil:
+    output.srvValueOutput = &srvValueOutput{Port: value.srv.port, Priority: value.srv.priority, Target: value.srv.target, Weight: value.srv.weight}
+  case value.caa != nil:
+    output.caaValueOutput = &caaValueOutput{Flag: value.caa.flag, Tag: value.caa.tag, Value: value.caa.value}
+  case value.characterStrings != nil:
+    output.txtValueOutput = &txtValueOutput{Strings: value.characterStrings, Text: value.Text()}
+  }
+
+  return output
+}
+func (hc *healthCheckSummary) Output() *healthCheckOutput {
+  output := &healthCheckOutput{
+    Checkers: make([]*healthCheckerOutput, 0, len(hc.observations)),
+    HealthyCheckers: hc.healthyCheckers,
+    Id: hc.id,
+    Inverted: hc.inverted,
+    Status: hc.status,
+    Target: hc.target,
+    Type: hc.checkType,
+  }
+
+  for _, observation := range hc.observations {
+    output.Checkers = append(output.Checkers, &healthCheckerOutput{
+      CheckedAt: observation.checkedAt,
+      Healthy: observation.healthy,
+      IpAddress: observation.ipAddress,
+      Region: observation.region,
+      Report: observation.report,
+    })
+  }
+
+  return output
+}
+func (res *requestResult) Output() *siteOutput {
+  now := time.Now()
+  output := &siteOutput{
+    Error: res.callError != nil,
+    ExpiryStatus: siteThresholds.ExpiryStatus(res, now),
+    RedirectsToHttps: res.redirectsToHttps,
+    Site: res.site,
+    SiteStatus: siteThresholds.SiteStatus(res, now),
+    Status: -1,
+  }
+
+  if res.rawResponse != nil {
+    output.Status = res.rawResponse.StatusCode
+  }
+  if res.responseEncrypted {
+    output.CipherSuite = res.cipherSuite
+    output.TlsVersion = res.tlsVersion
+    output.Cert = &certOutput{
+      DaysRemaining: DaysRemaining(res.certExpiration, now),
+      Expiration: res.certExpiration,
+      Fingerprint: res.certFingerprint,
+      Issuer: res.certIssuer,
+      Subject: res.certSubject,
+    }
+  }
+  if res.chain != nil {
+    output.Chain = res.chain.Output()
+  }
+  if res.hostname != nil {
+    output.Hostname = res.hostname.Output()
+  }
+  if res.verification != nil {
+    output.Verification = res.verification.Output()
+  }
+  if res.revocation != nil {
+    output.Revocation = res.revocation.Output()
+  }
+  if res.tlsProbe != nil {
+    output.TlsProbe = res.tlsProbe.Output()
+  }
+  if res.callError != nil {
+    output.ErrorMessage = res.callError.Error()
+  }
+
+  return output
+}
+func (d *registeredDomain) Output() *registeredDomainOutput {
+  return &registeredDomainOutput{
+    AutoRenew: d.autoRenew,
+    DaysUntilExpiration: d.DaysUntilExpiration(time.Now()),
+    Expiration: d.expiration,
+    HostedZoneIds: nonNilStrings(d.hostedZoneIds),
+    Name: d.name,
+    Nameservers: nonNilStrings(d.nameservers),
+    PrivacyProtection: privacyProtectionOutput{
+      Admin: d.adminPrivacy,
+      Registrant: d.registrantPrivacy,
+      Tech: d.techPrivacy,
+    },
+    TransferLock: d.transferLock,
+  }
+}
+func (report *delegationReport) Output() *delegationOutput {
+  output := &delegationOutput{
+    DelegatedNameservers: nonNilStrings(report.delegatedNs),
+    DuplicateZoneIds: nonNilStrings(report.duplicateZoneIds),
+    Error: report.err != nil,
+    Extra: nonNilStrings(report.extra),
+    HostedNameservers: nonNilStrings(report.hostedNs),
+    Missing: nonNilStrings(report.missing),
+    Status: report.status,
+    ZoneId: report.zoneId,
+    ZoneName: report.zoneName,
+  }
+
+  if report.err != nil {
+    output.ErrorMessage = report.err.Error()
+  }
+
+  return output
+}
+
+/*
+ *  Marshals a value without HTML escaping (record values are full of < > &
+ *  that don't need it).
+ */
+func marshalJson(value interface{}) string {
+  var buffer bytes.Buffer
+  encoder := json.NewEncoder(&buffer)
+
+  encoder.SetEscapeHTML(false)
+  //output types only hold strings, numbers, bools and times; this can't fail
+  encoder.Encode(value)
+
+  return strings.TrimSuffix(buffer.String(), "\n")
+}
+
+/*
+ *  Marshals a top level document: the schema version and a single collection.
+ */
+func serializeDocument(key string, items interface{}) string {
+  return marshalJson(map[string]interface{}{
+    "schemaVersion": outputSchemaVersion,
+    key: items,
+  })
+}
+
+/*
+ *  empty lists are printed as [] rather than null
+ */
+func nonNilStrings(values []string) []string {
+  if values == nil {
+    return []string{}
+  }
+
+  return values
+}
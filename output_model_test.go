@@ -0,0 +1,177 @@
+package main
+import (
+  "crypto/x509"
+  "encoding/json"
+  "errors"
+  "net/http"
+  "strings"
+  "testing"
+  "time"
+)
+
+/*
+ *  checks a decoded document against the parts of JSON Schema outputSchema
+ *  uses (type, required, properties, items, enum, $ref); enough to catch the
+ *  model and the schema drifting apart
+ */
+func validateSchema(schema map[string]interface{}, defs map[string]interface{}, value interface{}, path string) error {
+  if ref, isRef := schema["$ref"].(string); isRef {
+    return validateSchema(defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{}), defs, value, path)
+  }
+
+  if enum, hasEnum := schema["enum"].([]interface{}); hasEnum {
+    for _, allowed := range enum {
+      if allowed == value {
+        return nil
+      }
+    }
+    return errors.New(path + " is not one of the allowed values")
+  }
+
+  switch schema["type"] {
+  case "object":
+    object, isObject := value.(map[string]interface{})
+    if !isObject {
+      return errors.New(path + " should be an object")
+    }
+    required, _ := schema["required"].([]interface{})
+    for _, key := range required {
+      if _, found := object[key.(string)]; !found {
+        return errors.New(path + " is missing " + key.(string))
+      }
+    }
+    properties, _ := schema["properties"].(map[string]interface{})
+    for key, propertyValue := range object {
+      propertySchema, known := properties[key].(map[string]interface{})
+      if !known {
+        return errors.New(path + " has an undocumented property " + key)
+      }
+      if err := validateSchema(propertySchema, defs, propertyValue, path + "." + key); err != nil {
+        return err
+      }
+    }
+  case "array":
+    array, isArray := value.([]interface{})
+    if !isArray {
+      return errors.New(path + " should be an array")
+    }
+    for _, item := range array {
+      if err := validateSchema(schema["items"].(map[string]interface{}), defs, item, path + "[]"); err != nil {
+        return err
+      }
+    }
+  case "string":
+    if _, isString := value.(string); !isString {
+      return errors.New(path + " should be a string")
+    }
+  case "integer":
+    if number, isNumber := value.(float64); !isNumber || number != float64(int64(number)) {
+      return errors.New(path + " should be an integer")
+    }
+  case "boolean":
+    if _, isBool := value.(bool); !isBool {
+      return errors.New(path + " should be a boolean")
+    }
+  }
+
+  return nil
+}
+
+func TestOutputSchema(t *testing.T) {
+  var schema map[string]interface{}
+  weight := int64(0)
+  z := new(zone)
+  z.id = "Z1"
+  z.accountId = "123456789012"
+  z.SetName("dev.example.co.uk.")
+  recordCount := int64(12)
+  counted := &zone{id: "Z2", recordCount: &recordCount}
+  counted.SetName("example.com.")
+  mx := &record{name: "example.com", rrType: "MX", ttl: 300, values: []string{"10 mail.example.com."}}
+  weighted := &record{name: "www.example.com", rrType: "A", setIdentifier: "blue", weight: &weight, healthCheckId: "hc1", healthCheck: &healthCheckSummary{id: "hc1", status: "healthy", observations: []*healthCheckObservation{&healthCheckObservation{region: "us-east-1", healthy: true}}}}
+  _, intermediate, leaf := createTestChain(t)
+  site := &requestResult{site: "https://example.com", responseEncrypted: true, certSubject: "CN=example.com", certExpiration: time.Now(), chain: AnalyzeChain([]*x509.Certificate{leaf.cert, intermediate.cert}, nil, time.Now())}
+  site.hostname = MatchHostname(leaf.cert, "www.example.com")
+  site.verification = VerifySite(leaf.cert, site.chain, site.hostname, nil, false, time.Now())
+  site.revocation = &revocationReport{source: "ocsp", stapled: false, status: "revoked", revokedAt: time.Now(), reason: "keyCompromise", url: "http://ocsp.example.com"}
+  site.tlsProbe = &tlsProbeReport{
+    findings: []string{"deprecated protocol accepted: VersionTLS10"},
+    suites: []*tlsSuiteSupport{{suite: 0x000a, version: 0x0301, weakness: CipherSuiteWeakness(0x000a)}},
+    versions: []*tlsVersionSupport{{deprecated: true, supported: true, version: 0x0301}, {supported: false, version: 0x0304}},
+  }
+  report := &delegationReport{zoneId: "Z1", zoneName: "example.com", status: "unknown", err: errors.New("timeout")}
+  var checked strings.Builder
+  CheckSites(&checked, new(jsonFormatter), []string{"https://example.com"}, func(string) *requestResult { return site }, nil)
+  documents := []string{
+    checked.String(),
+    SerializeZones([]*zone{z, counted}),
+    (&recordset{"MX": []*record{mx}, "A": []*record{weighted}}).SerializeRecords("mx"),
+    (&recordset{"MX": []*record{mx}, "A": []*record{weighted}}).SerializeRecords("a"),
+    serializeDocument("sites", []*siteOutput{site.Output()}),
+    SerializeRegisteredDomains([]*registeredDomain{&registeredDomain{name: "example.com"}}),
+    SerializeDelegations([]*delegationReport{report}),
+    (&snapshotOutput{Provider: "route53", Zones: []*snapshotZoneOutput{&snapshotZoneOutput{Zone: z.Output(), Records: []*recordOutput{mx.Output()}}}}).Serialize(),
+    serializeDocument("changes", []*recordChangeOutput{newRecordChange("changed", z.Output(), mx.Output(), weighted.Output())}),
+  }
+
+  //test cases
+  //  1: the schema is valid JSON
+  //  2: every kind of document validates against it
+  //  3: zones whose records weren't counted leave the count out
+  if err := json.Unmarshal([]byte(outputSchema), &schema); err != nil {
+    t.Fatalf("tc1 - schema isn't valid JSON: %s", err.Error())
+  }
+
+  for _, document := range documents {
+    var decoded interface{}
+    json.Unmarshal([]byte(document), &decoded)
+    if err := validateSchema(schema, schema["$defs"].(map[string]interface{}), decoded, "$"); err != nil {
+      t.Errorf("tc2 - %s: %s", err.Error(), document)
+    }
+  }
+
+  if zones := SerializeZones([]*zone{z, counted}); strings.Count(zones, "\"recordCount\"") != 1 || !strings.Contains(zones, "\"recordCount\":12") {
+    t.Errorf("tc3 - expected a single record count: %s", zones)
+  }
+}
+
+func TestOutputEscaping(t *testing.T) {
+  var decodedRecord map[string]interface{}
+  var decodedSite map[string]interface{}
+  txt := &record{name: "example.com", rrType: "TXT", values: []string{"\"say \\\"hi\\\"\" \"tab\there\"", "caf\xc3\xa9 <&> \x01"}}
+  site := &requestResult{
+    callError: errors.New("dial \"example.com\": refused"),
+    certIssuer: "CN=Issuer \\, \"Quoted\"",
+    certSubject: "O=Société",
+    rawResponse: &http.Response{StatusCode: 200},
+    responseEncrypted: true,
+    site: "https://example.com",
+  }
+
+  //test cases
+  //  1: quotes, control characters and non-ASCII in record values survive a round trip
+  //  2: the parsed TXT strings are unescaped
+  //  3: quotes and backslashes in cert fields and errors survive a round trip
+  serialized := txt.Serialize()
+  if err := json.Unmarshal([]byte(serialized), &decodedRecord); err != nil {
+    t.Fatalf("tc1 - invalid JSON: %s", serialized)
+  }
+  values := decodedRecord["values"].([]interface{})
+  if values[0] != txt.values[0] || values[1] != txt.values[1] {
+    t.Errorf("tc1 - values changed in transit: %q", values)
+  }
+
+  parsedValues := decodedRecord["parsedValues"].([]interface{})
+  if parsedValues[0].(map[string]interface{})["text"] != "say \"hi\"tab\there" {
+    t.Errorf("tc2 - unexpected parsed value: %+v", parsedValues[0])
+  }
+
+  serialized = site.Serialize()
+  if err := json.Unmarshal([]byte(serialized), &decodedSite); err != nil {
+    t.Fatalf("tc3 - invalid JSON: %s", serialized)
+  }
+  cert := decodedSite["cert"].(map[string]interface{})
+  if cert["issuer"] != site.certIssuer || cert["subject"] != site.certSubject || decodedSite["errorMessage"] != site.callError.Error() {
+    t.Errorf("tc3 - unexpected site output: %s", serialized)
+  }
+}
@@ -0,0 +1,384 @@
+package main
+
+/*
+ *  JSON Schema (draft 2020-12) for every document printed in automatable mode;
+ *  printed with -schema. Keep it in step with output_model.go and bump
+ *  outputSchemaVersion alongside it.
+ */
+const outputSchema string = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://github.com/rdenson/domania/schema/1.8/output.json",
+  "title": "domania output",
+  "type": "object",
+  "required": ["schemaVersion"],
+  "properties": {
+    "schemaVersion": {"type": "string", "pattern": "^1\\.[0-9]+$"},
+    "zones": {"type": "array", "items": {"$ref": "#/$defs/zone"}},
+    "zoneRecords": {"type": "array", "items": {"$ref": "#/$defs/record"}},
+    "sites": {"type": "array", "items": {"$ref": "#/$defs/site"}},
+    "domains": {"type": "array", "items": {"$ref": "#/$defs/registeredDomain"}},
+    "delegations": {"type": "array", "items": {"$ref": "#/$defs/delegation"}},
+    "summary": {"$ref": "#/$defs/siteSummary"},
+    "snapshot": {"$ref": "#/$defs/snapshot"},
+    "changes": {"type": "array", "items": {"$ref": "#/$defs/recordChange"}},
+    "runs": {"type": "array", "items": {"$ref": "#/$defs/run"}},
+    "recordHistory": {"type": "array", "items": {"$ref": "#/$defs/recordVersion"}},
+    "siteChanges": {"type": "array", "items": {"$ref": "#/$defs/siteChange"}}
+  },
+  "oneOf": [
+    {"required": ["zones"]},
+    {"required": ["zoneRecords"]},
+    {"required": ["sites"]},
+    {"required": ["domains"]},
+    {"required": ["delegations"]},
+    {"required": ["snapshot"]},
+    {"required": ["changes"]},
+    {"required": ["runs"]},
+    {"required": ["recordHistory"]},
+    {"required": ["siteChanges"]}
+  ],
+  "$defs": {
+    "stringList": {"type": "array", "items": {"type": "string"}},
+    "zone": {
+      "type": "object",
+      "required": ["id", "domain", "tld", "subdomain", "registrableDomain", "publicSuffix"],
+      "properties": {
+        "id": {"type": "string"},
+        "domain": {"type": "string"},
+        "tld": {"type": "string"},
+        "subdomain": {"type": "string"},
+        "registrableDomain": {"type": "string"},
+        "publicSuffix": {"type": "string"},
+        "accountId": {"type": "string"},
+        "accountAlias": {"type": "string"},
+        "recordCount": {"type": "integer", "minimum": 0}
+      }
+    },
+    "record": {
+      "type": "object",
+      "required": ["name", "isAlias", "values"],
+      "properties": {
+        "name": {"type": "string"},
+        "type": {"type": "string"},
+        "isAlias": {"type": "boolean"},
+        "zoneReference": {"type": "string"},
+        "proxied": {"type": "boolean"},
+        "evaluateTargetHealth": {"type": "boolean"},
+        "ttl": {"type": "integer", "minimum": 0},
+        "setIdentifier": {"type": "string"},
+        "weight": {"type": "integer", "minimum": 0},
+        "region": {"type": "string"},
+        "geoLocation": {
+          "type": "object",
+          "required": ["continentCode", "countryCode", "subdivisionCode"],
+          "properties": {
+            "continentCode": {"type": "string"},
+            "countryCode": {"type": "string"},
+            "subdivisionCode": {"type": "string"}
+          }
+        },
+        "failover": {"enum": ["PRIMARY", "SECONDARY"]},
+        "multiValueAnswer": {"type": "boolean"},
+        "healthCheckId": {"type": "string"},
+        "healthCheck": {"$ref": "#/$defs/healthCheck"},
+        "values": {"$ref": "#/$defs/stringList"},
+        "parsedValues": {"type": "array", "items": {"$ref": "#/$defs/recordValue"}}
+      }
+    },
+    "recordValue": {
+      "type": "object",
+      "required": ["raw"],
+      "properties": {
+        "raw": {"type": "string"},
+        "preference": {"type": "integer"},
+        "exchange": {"type": "string"},
+        "priority": {"type": "integer"},
+        "weight": {"type": "integer"},
+        "port": {"type": "integer"},
+        "target": {"type": "string"},
+        "flag": {"type": "integer"},
+        "tag": {"type": "string"},
+        "value": {"type": "string"},
+        "strings": {"$ref": "#/$defs/stringList"},
+        "text": {"type": "string"}
+      }
+    },
+    "healthCheck": {
+      "type": "object",
+      "required": ["id", "type", "target", "inverted", "status", "healthyCheckers", "checkers"],
+      "properties": {
+        "id": {"type": "string"},
+        "type": {"type": "string"},
+        "target": {"type": "string"},
+        "inverted": {"type": "boolean"},
+        "status": {"enum": ["healthy", "unhealthy", "unknown"]},
+        "healthyCheckers": {"type": "integer", "minimum": 0},
+        "checkers": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": ["region", "ipAddress", "healthy", "checkedAt", "report"],
+            "properties": {
+              "region": {"type": "string"},
+              "ipAddress": {"type": "string"},
+              "healthy": {"type": "boolean"},
+              "checkedAt": {"type": "string", "format": "date-time"},
+              "report": {"type": "string"}
+            }
+          }
+        }
+      }
+    },
+    "site": {
+      "type": "object",
+      "required": ["site", "status", "redirectsToHttps", "expiryStatus", "siteStatus", "error"],
+      "properties": {
+        "site": {"type": "string"},
+        "status": {"type": "integer"},
+        "redirectsToHttps": {"type": "boolean"},
+        "expiryStatus": {"$ref": "#/$defs/siteStatus"},
+        "siteStatus": {"$ref": "#/$defs/siteStatus"},
+        "cipherSuite": {"type": "string"},
+        "tlsVersion": {"type": "string"},
+        "cert": {
+          "type": "object",
+          "required": ["issuer", "subject", "expiration", "daysRemaining", "fingerprint"],
+          "properties": {
+            "issuer": {"type": "string"},
+            "subject": {"type": "string"},
+            "expiration": {"type": "string", "format": "date-time"},
+            "daysRemaining": {"type": "integer"},
+            "fingerprint": {"type": "string"}
+          }
+        },
+        "chain": {
+          "type": "object",
+          "required": ["presented", "verified", "verifiedChains", "issues"],
+          "properties": {
+            "presented": {"type": "array", "items": {"$ref": "#/$defs/certDetail"}},
+            "verified": {"type": "boolean"},
+            "verifiedChains": {"type": "array", "items": {"type": "array", "items": {"$ref": "#/$defs/certDetail"}}},
+            "issues": {"$ref": "#/$defs/stringList"}
+          }
+        },
+        "hostname": {
+          "type": "object",
+          "required": ["hostname", "covered", "matchedNames", "matchedWildcards", "subjectAltNames"],
+          "properties": {
+            "hostname": {"type": "string"},
+            "covered": {"type": "boolean"},
+            "matchedNames": {"$ref": "#/$defs/stringList"},
+            "matchedWildcards": {"$ref": "#/$defs/stringList"},
+            "subjectAltNames": {"$ref": "#/$defs/stringList"}
+          }
+        },
+        "verification": {
+          "type": "object",
+          "required": ["verified", "insecureFallback", "failures"],
+          "properties": {
+            "verified": {"type": "boolean"},
+            "insecureFallback": {"type": "boolean"},
+            "failures": {"type": "array", "items": {"enum": ["unknownAuthority", "expired", "notYetValid", "hostnameMismatch", "invalid"]}},
+            "errorMessage": {"type": "string"}
+          }
+        },
+        "revocation": {
+          "type": "object",
+          "required": ["status", "source", "stapled"],
+          "properties": {
+            "status": {"enum": ["good", "revoked", "unknown"]},
+            "source": {"enum": ["stapled", "ocsp", "crl", "none"]},
+            "stapled": {"type": "boolean"},
+            "url": {"type": "string"},
+            "producedAt": {"type": "string", "format": "date-time"},
+            "thisUpdate": {"type": "string", "format": "date-time"},
+            "nextUpdate": {"type": "string", "format": "date-time"},
+            "revokedAt": {"type": "string", "format": "date-time"},
+            "reason": {"type": "string"},
+            "errorMessage": {"type": "string"}
+          }
+        },
+        "tlsProbe": {
+          "type": "object",
+          "required": ["versions", "cipherSuites", "findings"],
+          "properties": {
+            "versions": {
+              "type": "array",
+              "items": {
+                "type": "object",
+                "required": ["version", "supported", "deprecated"],
+                "properties": {
+                  "version": {"type": "string"},
+                  "supported": {"type": "boolean"},
+                  "deprecated": {"type": "boolean"}
+                }
+              }
+            },
+            "cipherSuites": {
+              "type": "array",
+              "items": {
+                "type": "object",
+                "required": ["version", "suite", "weak"],
+                "properties": {
+                  "version": {"type": "string"},
+                  "suite": {"type": "string"},
+                  "weak": {"type": "boolean"},
+                  "weakness": {"type": "string"}
+                }
+              }
+            },
+            "findings": {"$ref": "#/$defs/stringList"}
+          }
+        },
+        "error": {"type": "boolean"},
+        "errorMessage": {"type": "string"}
+      }
+    },
+    "certDetail": {
+      "type": "object",
+      "required": ["subject", "issuer", "serialNumber", "notBefore", "notAfter", "keyType", "keySize", "signatureAlgorithm", "isCA", "sha256Fingerprint"],
+      "properties": {
+        "subject": {"type": "string"},
+        "issuer": {"type": "string"},
+        "serialNumber": {"type": "string"},
+        "notBefore": {"type": "string", "format": "date-time"},
+        "notAfter": {"type": "string", "format": "date-time"},
+        "keyType": {"type": "string"},
+        "keySize": {"type": "integer", "minimum": 0},
+        "signatureAlgorithm": {"type": "string"},
+        "isCA": {"type": "boolean"},
+        "sha256Fingerprint": {"type": "string"}
+      }
+    },
+    "siteSummary": {
+      "type": "object",
+      "required": ["sites", "completed", "errors", "encrypted", "redirectsToHttps", "interrupted", "startedAt", "durationMs", "warningDays", "criticalDays", "status", "exitCode"],
+      "properties": {
+        "sites": {"type": "integer", "minimum": 0},
+        "completed": {"type": "integer", "minimum": 0},
+        "errors": {"type": "integer", "minimum": 0},
+        "encrypted": {"type": "integer", "minimum": 0},
+        "redirectsToHttps": {"type": "integer", "minimum": 0},
+        "interrupted": {"type": "boolean"},
+        "startedAt": {"type": "string", "format": "date-time"},
+        "durationMs": {"type": "integer", "minimum": 0},
+        "warningDays": {"type": "integer", "minimum": 0},
+        "criticalDays": {"type": "integer", "minimum": 0},
+        "status": {"$ref": "#/$defs/siteStatus"},
+        "exitCode": {"enum": [0, 1, 2, 3]}
+      }
+    },
+    "siteStatus": {"enum": ["ok", "warning", "critical", "unknown"]
+    },
+    "registeredDomain": {
+      "type": "object",
+      "required": ["name", "expiration", "daysUntilExpiration", "autoRenew", "transferLock", "privacyProtection", "nameservers", "hostedZoneIds"],
+      "properties": {
+        "name": {"type": "string"},
+        "expiration": {"type": "string", "format": "date-time"},
+        "daysUntilExpiration": {"type": "integer"},
+        "autoRenew": {"type": "boolean"},
+        "transferLock": {"type": "boolean"},
+        "privacyProtection": {
+          "type": "object",
+          "required": ["admin", "registrant", "tech"],
+          "properties": {
+            "admin": {"type": "boolean"},
+            "registrant": {"type": "boolean"},
+            "tech": {"type": "boolean"}
+          }
+        },
+        "nameservers": {"$ref": "#/$defs/stringList"},
+        "hostedZoneIds": {"$ref": "#/$defs/stringList"}
+      }
+    },
+    "delegation": {
+      "type": "object",
+      "required": ["zoneId", "zoneName", "status", "hostedNameservers", "delegatedNameservers", "missing", "extra", "duplicateZoneIds", "error"],
+      "properties": {
+        "zoneId": {"type": "string"},
+        "zoneName": {"type": "string"},
+        "status": {"enum": ["consistent", "partial", "mismatch", "undelegated", "unknown"]},
+        "hostedNameservers": {"$ref": "#/$defs/stringList"},
+        "delegatedNameservers": {"$ref": "#/$defs/stringList"},
+        "missing": {"$ref": "#/$defs/stringList"},
+        "extra": {"$ref": "#/$defs/stringList"},
+        "duplicateZoneIds": {"$ref": "#/$defs/stringList"},
+        "error": {"type": "boolean"},
+        "errorMessage": {"type": "string"}
+      }
+    },
+    "snapshot": {
+      "type": "object",
+      "required": ["takenAt", "provider", "zones"],
+      "properties": {
+        "takenAt": {"type": "string", "format": "date-time"},
+        "provider": {"type": "string"},
+        "zones": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": ["zone", "records"],
+            "properties": {
+              "zone": {"$ref": "#/$defs/zone"},
+              "records": {"type": "array", "items": {"$ref": "#/$defs/record"}}
+            }
+          }
+        }
+      }
+    },
+    "recordChange": {
+      "type": "object",
+      "required": ["change", "zoneId", "zoneName", "name", "type", "addedValues", "removedValues", "details"],
+      "properties": {
+        "change": {"enum": ["added", "removed", "changed"]},
+        "zoneId": {"type": "string"},
+        "zoneName": {"type": "string"},
+        "name": {"type": "string"},
+        "type": {"type": "string"},
+        "setIdentifier": {"type": "string"},
+        "oldTtl": {"type": "integer", "minimum": 0},
+        "newTtl": {"type": "integer", "minimum": 0},
+        "addedValues": {"$ref": "#/$defs/stringList"},
+        "removedValues": {"$ref": "#/$defs/stringList"},
+        "details": {"$ref": "#/$defs/stringList"}
+      }
+    },
+    "run": {
+      "type": "object",
+      "required": ["startedAt", "arguments"],
+      "properties": {
+        "startedAt": {"type": "string", "format": "date-time"},
+        "arguments": {"$ref": "#/$defs/stringList"}
+      }
+    },
+    "recordVersion": {
+      "type": "object",
+      "required": ["zoneId", "name", "type", "present", "firstSeen", "lastSeen", "observations"],
+      "properties": {
+        "zoneId": {"type": "string"},
+        "name": {"type": "string"},
+        "type": {"type": "string"},
+        "setIdentifier": {"type": "string"},
+        "present": {"type": "boolean"},
+        "firstSeen": {"type": "string", "format": "date-time"},
+        "lastSeen": {"type": "string", "format": "date-time"},
+        "observations": {"type": "integer", "minimum": 1},
+        "record": {"$ref": "#/$defs/record"}
+      }
+    },
+    "siteChange": {
+      "type": "object",
+      "required": ["site", "field", "before", "after", "lastSeenBefore", "changedAt"],
+      "properties": {
+        "site": {"type": "string"},
+        "field": {"enum": ["certFingerprint", "redirectsToHttps"]},
+        "before": {"type": "string"},
+        "after": {"type": "string"},
+        "lastSeenBefore": {"type": "string", "format": "date-time"},
+        "changedAt": {"type": "string", "format": "date-time"}
+      }
+    }
+  }
+}
+`
@@ -2,8 +2,11 @@ package main
 import (
   "crypto/sha1"
   "crypto/tls"
+  "fmt"
+  "io"
   "net/http"
   "net/url"
+  "os"
   "strconv"
   "strings"
   "time"
@@ -62,10 +65,24 @@ var insecureTransport *http.Transport = &http.Transport{
     InsecureSkipVerify: true,
   },
 }
+//a client per transport; sites are checked concurrently, so neither is changed after this
 var httpClient *http.Client = &http.Client{
+  CheckRedirect: keepRedirectResponse,
   Transport: standardTransport,
   Timeout: 30 * time.Second,
 }
+var insecureHttpClient *http.Client = &http.Client{
+  CheckRedirect: keepRedirectResponse,
+  Transport: insecureTransport,
+  Timeout: 30 * time.Second,
+}
+
+/*
+ *  intercepts redirects, just returning the initial response
+ */
+func keepRedirectResponse(req *http.Request, via []*http.Request) error {
+  return http.ErrUseLastResponse
+}
 
 /*
  *  Given an HTTP response, does the request redirect us and does it redirect us
@@ -117,22 +134,15 @@ func FormatUrl(host string, secure bool) string {
 }
 
 func LoadRequest(site string, insecure bool) (*http.Response, error) {
-  //set TLS configuration...
+  //pick the TLS configuration...
+  client := httpClient
   if insecure {
-    httpClient.Transport = insecureTransport
-  } else {
-    httpClient.Transport = standardTransport
-  }
-
-  //intercept the request redirect
-  httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-    //just return the initial response
-    return http.ErrUseLastResponse
+    client = insecureHttpClient
   }
 
   req, _ := http.NewRequest("GET", site, nil)
   req.Close = true
-  return httpClient.Do(req)
+  return client.Do(req)
 }
 
 /*
@@ -140,6 +150,10 @@ func LoadRequest(site string, insecure bool) (*http.Response, error) {
  */
 type requestResult struct {
   callError error
+  chain *chainReport
+  hostname *hostnameReport
+  insecureFallback bool
+  strictError error
   certExpiration time.Time
   certFingerprint string
   certIssuer string
@@ -148,8 +162,11 @@ type requestResult struct {
   redirects bool
   redirectsToHttps bool
   responseEncrypted bool
+  revocation *revocationReport
   site string
+  tlsProbe *tlsProbeReport
   tlsVersion string
+  verification *verificationReport
 
   rawResponse *http.Response
 }
@@ -160,16 +177,25 @@ func (res *requestResult) AnalyzeTLS() {
     res.cipherSuite = cipherSuitesByCode[res.rawResponse.TLS.CipherSuite]
     res.tlsVersion = tlsVersionsByCode[res.rawResponse.TLS.Version]
 
-    itr := 0
+    //the leaf should be first; servers that send it later still get it found
     certs := res.rawResponse.TLS.PeerCertificates
-    for certs[itr].IsCA {
-      itr++
+    res.chain = AnalyzeChain(certs, chainRoots, time.Now())
+    if len(certs) == 0 {
+      return
+    }
+    leaf := certs[LeafIndex(certs)]
+
+    //is it trusted for the host this request went to?
+    if res.rawResponse.Request != nil && res.rawResponse.Request.URL != nil {
+      res.hostname = MatchHostname(leaf, res.rawResponse.Request.URL.Hostname())
     }
+    res.verification = VerifySite(leaf, res.chain, res.hostname, res.strictError, res.insecureFallback, time.Now())
+    res.revocation = CheckRevocation(leaf, IssuerOf(leaf, res.chain), res.rawResponse.TLS.OCSPResponse, time.Now())
 
-    res.certExpiration = certs[itr].NotAfter
-    res.certIssuer = certs[itr].Issuer.String()
-    res.certSubject = certs[itr].Subject.String()
-    fpBytes := sha1.Sum(certs[itr].Raw)
+    res.certExpiration = leaf.NotAfter
+    res.certIssuer = leaf.Issuer.String()
+    res.certSubject = leaf.Subject.String()
+    fpBytes := sha1.Sum(leaf.Raw)
     for i:=0; i<len(fpBytes); i++ {
       fingerprint.WriteString(strconv.FormatInt(int64(fpBytes[i]), 16))
       if i < (len(fpBytes) - 1) {
@@ -181,50 +207,14 @@ func (res *requestResult) AnalyzeTLS() {
   }
 }
 func (res *requestResult) Serialize() string {
-  var jsonString strings.Builder
-  var refinedStatus int = -1
-
-  if res.rawResponse != nil {
-    refinedStatus = res.rawResponse.StatusCode
-  }
-
-  jsonString.WriteString("{")
-  jsonString.WriteString("\"site\":\"" + res.site + "\",")
-  jsonString.WriteString("\"status\":" + strconv.Itoa(refinedStatus) + ",")
-  jsonString.WriteString("\"redirectsToHttps\":" + strconv.FormatBool(res.redirectsToHttps) + ",")
-  if res.responseEncrypted {
-    //finding some things in the issuer and subject that we need to escape...
-    cleanIssuer := strings.Replace(res.certIssuer, "\\", "\\\\", -1)
-    cleanSubject := strings.Replace(res.certSubject, "\\", "\\\\", -1)
-
-    jsonString.WriteString("\"cipherSuite\":\"" + res.cipherSuite + "\",")
-    jsonString.WriteString("\"tlsVersion\":\"" + res.tlsVersion + "\",")
-    //placing cert specific datapoint in a separate object
-    jsonString.WriteString("\"cert\":{")
-    jsonString.WriteString("\"issuer\":\"" + cleanIssuer + "\",")
-    jsonString.WriteString("\"subject\":\"" + cleanSubject + "\",")
-    jsonString.WriteString("\"expiration\":\"" + res.certExpiration.String() + "\",")
-    jsonString.WriteString("\"fingerprint\":\"" + res.certFingerprint + "\"")
-    jsonString.WriteString("},")
-  }
-
-  jsonString.WriteString("\"error\":" + strconv.FormatBool(res.callError != nil))
-  //if there is an error however, plebeian, include during serialization
-  if res.callError != nil {
-    jsonString.WriteString(",")
-    jsonString.WriteString("\"errorMessage\":\"" + res.callError.Error() + "\"")
-  }
-
-  jsonString.WriteString("}")
-
-  return jsonString.String()
+  return marshalJson(res.Output())
 }
 
 /*
  *  Get security information from an HTTP GET request. Tries to understand the state
  *  of a site's security by examining redirect behavior and TLS status.
  */
-func ParseSite(uri string) string {
+func CheckSite(uri string) *requestResult {
   var parseResults *requestResult = new(requestResult)
   var response *http.Response
   var requestError error
@@ -241,7 +231,10 @@ func ParseSite(uri string) string {
 
   //error anticipation (request could come from one of the calls above)
   if requestError != nil && strings.Contains(requestError.Error(), "x509:") {
-    //error mentions something about the cert, hit it again and don't try to verify the cert
+    //error mentions something about the cert, hit it again and don't try to verify
+    //the cert (the result says so, along with what was wrong)
+    parseResults.insecureFallback = true
+    parseResults.strictError = requestError
     response, requestError = LoadRequest(FormatUrl(parseResults.site, true), true)
   }
 
@@ -250,12 +243,93 @@ func ParseSite(uri string) string {
   parseResults.callError = requestError
   parseResults.AnalyzeTLS()
 
-  return parseResults.Serialize()
+  return parseResults
+}
+
+/*
+ *  CheckSite, serialized
+ */
+func ParseSite(uri string) string {
+  return CheckSite(uri).Serialize()
 }
 
 /*
  * Sends parsed site results to a channel for all of your CSP needs
  */
-func ChanneledParseSite(uri string, ch chan<- string) {
-  ch <- ParseSite(uri)
+func ChanneledParseSite(uri string, ch chan<- *requestResult) {
+  channeledCheck(uri, CheckSite, ch)
+}
+
+/*
+ *  Runs a check and sends its result; a check that panics still sends one (as an
+ *  error) so whoever is counting results isn't left waiting.
+ */
+func channeledCheck(uri string, check func(string) *requestResult, ch chan<- *requestResult) {
+  defer func() {
+    if r := recover(); r != nil {
+      ch <- &requestResult{site: uri, callError: fmt.Errorf("site check panicked: %v", r)}
+    }
+  }()
+
+  ch <- check(uri)
+}
+
+/*
+ *  Checks every site concurrently and writes each result with the formatter as
+ *  soon as it's in, then a summary with the worst expiry status (and the exit
+ *  code for it). Anything arriving on interrupt (SIGINT, SIGTERM) stops the
+ *  wait; the output is still closed off properly, with the summary marked as
+ *  interrupted.
+ */
+func CheckSites(w io.Writer, formatter outputFormatter, sites []string, check func(string) *requestResult, interrupt <-chan os.Signal) (*siteSummaryOutput, error) {
+  summary := &siteSummaryOutput{
+    CriticalDays: siteThresholds.criticalDays,
+    Sites: len(sites),
+    StartedAt: time.Now(),
+    Status: statusOk,
+    WarningDays: siteThresholds.warningDays,
+  }
+  batch := make(chan *requestResult, len(sites))
+
+  if err := formatter.Begin(w, "sites"); err != nil {
+    return summary, err
+  }
+
+  for _, site := range sites {
+    go channeledCheck(site, check, batch)
+  }
+
+  for summary.Completed < len(sites) && !summary.Interrupted {
+    select {
+    case result := <- batch:
+      output := result.Output()
+      summary.Completed++
+      if output.Error {
+        summary.Errors++
+      }
+      if output.Cert != nil {
+        summary.Encrypted++
+      }
+      if output.RedirectsToHttps {
+        summary.RedirectsToHttps++
+      }
+      summary.Status = WorstStatus(summary.Status, output.SiteStatus)
+
+      if err := formatter.WriteItem(output); err != nil {
+        return summary, err
+      }
+    case <- interrupt:
+      summary.Interrupted = true
+    }
+  }
+
+  summary.DurationMs = int64(time.Since(summary.StartedAt) / time.Millisecond)
+  summary.ExitCode = statusExitCodes[summary.Status]
+  if withSummary, supported := formatter.(summaryFormatter); supported {
+    if err := withSummary.WriteSummary(summary); err != nil {
+      return summary, err
+    }
+  }
+
+  return summary, formatter.End()
 }
@@ -1,6 +1,9 @@
 package main
 import (
+  "encoding/json"
+  "errors"
   "net/http"
+  "os"
   "strings"
   "testing"
 )
@@ -87,3 +90,59 @@ func TestFormatUrl(t *testing.T) {
     t.Errorf("tc5 - expected \"http\" scheme, received: \"%s\"", tc5)
   }
 }
+
+func TestCheckSites(t *testing.T) {
+  var output strings.Builder
+  var summaryLine map[string]*siteSummaryOutput
+  check := func(site string) *requestResult {
+    if site == "broken.example.com" {
+      var certs []int
+      _ = certs[1]
+    }
+    if site == "down.example.com" {
+      return &requestResult{site: site, callError: errors.New("connection refused")}
+    }
+    return &requestResult{site: site, redirectsToHttps: true, rawResponse: &http.Response{StatusCode: 200}}
+  }
+
+  //test cases
+  //  1: each site is written on its own line, a panicking check included
+  //  2: the last line is a summary with counts
+  summary, err := CheckSites(&output, new(ndjsonFormatter), []string{"www.example.com", "broken.example.com", "down.example.com"}, check, nil)
+  lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+  if err != nil || len(lines) != 4 || !strings.Contains(output.String(), "site check panicked") {
+    t.Errorf("tc1 - unexpected output: %s (error: %v)", output.String(), err)
+  }
+
+  json.Unmarshal([]byte(lines[len(lines) - 1]), &summaryLine)
+  if summaryLine["summary"] == nil || summaryLine["summary"].Completed != 3 || summaryLine["summary"].Errors != 2 || summaryLine["summary"].RedirectsToHttps != 1 || summary.Interrupted {
+    t.Errorf("tc2 - unexpected summary: %s", lines[len(lines) - 1])
+  }
+}
+
+func TestCheckSitesInterrupted(t *testing.T) {
+  var document map[string]interface{}
+  var output strings.Builder
+  interrupt := make(chan os.Signal, 1)
+  never := make(chan bool)
+  defer close(never)
+  check := func(site string) *requestResult {
+    if site == "slow.example.com" {
+      <-never
+    }
+    return &requestResult{site: site}
+  }
+
+  //test cases
+  //  1: an interrupt leaves a complete json document behind
+  //  2: the summary counts what finished and is marked interrupted
+  interrupt <- os.Interrupt
+  summary, err := CheckSites(&output, new(jsonFormatter), []string{"slow.example.com"}, check, interrupt)
+  if err != nil || json.Unmarshal([]byte(output.String()), &document) != nil {
+    t.Errorf("tc1 - invalid document: %s (error: %v)", output.String(), err)
+  }
+
+  if !summary.Interrupted || summary.Completed != 0 || document["summary"] == nil {
+    t.Errorf("tc2 - unexpected summary: %+v", summary)
+  }
+}
@@ -0,0 +1,110 @@
+package main
+
+import(
+  "bufio"
+  "os"
+  "strings"
+
+  "golang.org/x/net/idna"
+  "golang.org/x/net/publicsuffix"
+)
+
+/*
+ *  public suffix list - knows which part of a name is a public suffix (com,
+ *  co.uk, com.au, github.io...) so the registrable domain can be found
+ */
+type publicSuffixList interface {
+  PublicSuffix(name string) string
+}
+
+//the list used when splitting zone names; replaced by a local file with -psl
+var publicSuffixes publicSuffixList = embeddedSuffixList{}
+
+/*
+ *  snapshot of the list compiled into golang.org/x/net/publicsuffix
+ */
+type embeddedSuffixList struct {}
+func (embeddedSuffixList) PublicSuffix(name string) string {
+  suffix, _ := publicsuffix.PublicSuffix(name)
+
+  return suffix
+}
+
+/*
+ *  list read from a local copy of public_suffix_list.dat; rules, wildcard rules
+ *  (*.ck) and exception rules (!www.ck) are kept apart, without their markers
+ */
+type fileSuffixList struct {
+  exceptions map[string]bool
+  rules map[string]bool
+  wildcards map[string]bool
+}
+
+/*
+ *  Reads a list in the format published at https://publicsuffix.org/list/ so a
+ *  newer list can be used without rebuilding.
+ */
+func LoadPublicSuffixList(path string) (*fileSuffixList, error) {
+  list := &fileSuffixList{
+    exceptions: make(map[string]bool),
+    rules: make(map[string]bool),
+    wildcards: make(map[string]bool),
+  }
+
+  listFile, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+
+  defer listFile.Close()
+  scanner := bufio.NewScanner(listFile)
+  for scanner.Scan() {
+    //only the first word on a line is the rule
+    fields := strings.Fields(scanner.Text())
+    if len(fields) == 0 || strings.HasPrefix(fields[0], "//") {
+      continue
+    }
+
+    rule := fields[0]
+    target := list.rules
+    if strings.HasPrefix(rule, "!") {
+      rule, target = rule[1:], list.exceptions
+    } else if strings.HasPrefix(rule, "*.") {
+      rule, target = rule[2:], list.wildcards
+    }
+
+    //rules are published in unicode, zone names arrive punycoded
+    if ascii, err := idna.ToASCII(rule); err == nil {
+      rule = ascii
+    }
+    target[strings.ToLower(rule)] = true
+  }
+
+  return list, scanner.Err()
+}
+
+/*
+ *  Applies the list's algorithm: an exception rule wins outright, otherwise the
+ *  matching rule with the most labels does; with no match at all the last label
+ *  is the suffix.
+ */
+func (list *fileSuffixList) PublicSuffix(name string) string {
+  labels := strings.Split(strings.ToLower(strings.TrimSuffix(name, ".")), ".")
+
+  for i := range labels {
+    if list.exceptions[strings.Join(labels[i:], ".")] {
+      return strings.Join(labels[i + 1:], ".")
+    }
+  }
+
+  for i := range labels {
+    if list.rules[strings.Join(labels[i:], ".")] {
+      return strings.Join(labels[i:], ".")
+    }
+    if i + 1 < len(labels) && list.wildcards[strings.Join(labels[i + 1:], ".")] {
+      return strings.Join(labels[i:], ".")
+    }
+  }
+
+  return labels[len(labels) - 1]
+}
@@ -0,0 +1,105 @@
+package main
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "testing"
+)
+
+//a cut down public_suffix_list.dat
+const testSuffixList = `// ===BEGIN ICANN DOMAINS===
+com
+uk
+co.uk
+*.ck
+!www.ck
+
+// ===BEGIN PRIVATE DOMAINS===
+github.io    trailing words are ignored
+`
+
+func TestEmbeddedSuffixList(t *testing.T) {
+  list := embeddedSuffixList{}
+
+  //test cases
+  //  1: multi-label suffixes are recognized
+  //  2: unlisted tlds fall back to the last label
+  if suffix := list.PublicSuffix("dev.example.com.au"); suffix != "com.au" {
+    t.Errorf("tc1 - expected com.au, received: %s", suffix)
+  }
+
+  if suffix := list.PublicSuffix("example.notarealtld"); suffix != "notarealtld" {
+    t.Errorf("tc2 - expected notarealtld, received: %s", suffix)
+  }
+}
+
+func TestLoadPublicSuffixList(t *testing.T) {
+  dir, _ := ioutil.TempDir("", "domania-psl")
+  defer os.RemoveAll(dir)
+  path := filepath.Join(dir, "public_suffix_list.dat")
+  ioutil.WriteFile(path, []byte(testSuffixList), 0644)
+
+  list, err := LoadPublicSuffixList(path)
+  if err != nil {
+    t.Fatalf("unable to load the list: %s", err.Error())
+  }
+
+  //test cases
+  //  1: the rule with the most labels wins
+  //  2: wildcard rules cover any label
+  //  3: exception rules beat wildcards
+  //  4: unlisted names fall back to the last label
+  //  5: private rules are read too
+  //  6: a missing file is an error
+  if suffix := list.PublicSuffix("www.example.co.uk"); suffix != "co.uk" {
+    t.Errorf("tc1 - expected co.uk, received: %s", suffix)
+  }
+
+  if suffix := list.PublicSuffix("example.anything.ck"); suffix != "anything.ck" {
+    t.Errorf("tc2 - expected anything.ck, received: %s", suffix)
+  }
+
+  if suffix := list.PublicSuffix("www.ck"); suffix != "ck" {
+    t.Errorf("tc3 - expected ck, received: %s", suffix)
+  }
+
+  if suffix := list.PublicSuffix("example.net."); suffix != "net" {
+    t.Errorf("tc4 - expected net, received: %s", suffix)
+  }
+
+  if suffix := list.PublicSuffix("rdenson.github.io"); suffix != "github.io" {
+    t.Errorf("tc5 - expected github.io, received: %s", suffix)
+  }
+
+  if _, err = LoadPublicSuffixList(filepath.Join(dir, "missing.dat")); err == nil {
+    t.Errorf("tc6 - expected an error for a missing file")
+  }
+}
+
+func TestHzSortSubdomains(t *testing.T) {
+  var zones []*zone
+  var sortedNames []string
+  expectedNames := []string{"example.co.uk", "www.example.co.uk", "example.com", "dev.example.com", "other.com"}
+
+  for _, name := range []string{"www.example.co.uk", "other.com", "dev.example.com", "example.co.uk", "example.com"} {
+    z := new(zone)
+    z.SetName(name)
+    zones = append(zones, z)
+  }
+
+  //test cases
+  //  1: zones group by public suffix, then registrable domain, apex first
+  HzSort(zones, "subdomain")
+  HzSort(zones, "domain")
+  HzSort(zones, "tld")
+  for _, z := range zones {
+    sortedNames = append(sortedNames, z.DomainToString())
+  }
+
+  for i := range expectedNames {
+    if sortedNames[i] != expectedNames[i] {
+      t.Errorf("tc1 - expected %v, received: %v", expectedNames, sortedNames)
+      break
+    }
+  }
+}
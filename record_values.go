@@ -0,0 +1,176 @@
+package main
+
+import(
+  "errors"
+  "strconv"
+  "strings"
+  "unicode"
+)
+
+/*
+ *  record value - a single value as the provider presents it (raw) and, for
+ *  record types with structure, its fields; only the field matching the
+ *  record's type is set
+ *  note: TXT (and SPF) values are lists of character-strings, each at most 255
+ *        bytes on the wire; long DKIM keys are split across several
+ */
+type recordValue struct {
+  caa *caaValue
+  characterStrings []string
+  mx *mxValue
+  raw string
+  srv *srvValue
+}
+
+/*
+ *  MX - mail exchange and its preference (lower is preferred)
+ */
+type mxValue struct {
+  exchange string
+  preference int
+}
+
+/*
+ *  SRV - service location (RFC 2782)
+ */
+type srvValue struct {
+  port int
+  priority int
+  target string
+  weight int
+}
+
+/*
+ *  CAA - certification authority authorization (RFC 8659), eg. 0 issue "letsencrypt.org"
+ */
+type caaValue struct {
+  flag int
+  tag string
+  value string
+}
+
+/*
+ *  Parses a value in zone file presentation format (what route53 and zone
+ *  transfers hand back). Types without structure, or values that don't parse,
+ *  only carry the raw text.
+ */
+func ParseRecordValue(recordType string, raw string) *recordValue {
+  value := &recordValue{raw: raw}
+
+  switch strings.ToUpper(recordType) {
+  case "MX":
+    fields := strings.Fields(raw)
+    if len(fields) == 2 {
+      if preference, err := strconv.Atoi(fields[0]); err == nil {
+        value.mx = &mxValue{exchange: fields[1], preference: preference}
+      }
+    }
+  case "SRV":
+    fields := strings.Fields(raw)
+    if len(fields) == 4 {
+      priority, priorityErr := strconv.Atoi(fields[0])
+      weight, weightErr := strconv.Atoi(fields[1])
+      port, portErr := strconv.Atoi(fields[2])
+      if priorityErr == nil && weightErr == nil && portErr == nil {
+        value.srv = &srvValue{port: port, priority: priority, target: fields[3], weight: weight}
+      }
+    }
+  case "CAA":
+    //flag and tag are plain words (separated by any whitespace), the rest is
+    //the value as a character-string
+    fields := strings.Fields(raw)
+    if len(fields) >= 3 {
+      rest := strings.TrimLeftFunc(strings.TrimSpace(raw)[len(fields[0]):], unicode.IsSpace)
+      rest = strings.TrimLeftFunc(rest[len(fields[1]):], unicode.IsSpace)
+      flag, flagErr := strconv.Atoi(fields[0])
+      caaStrings, stringsErr := ParseCharacterStrings(rest)
+      if flagErr == nil && stringsErr == nil && len(caaStrings) == 1 {
+        value.caa = &caaValue{flag: flag, tag: fields[1], value: caaStrings[0]}
+      }
+    }
+  case "TXT", "SPF":
+    if txtStrings, err := ParseCharacterStrings(raw); err == nil {
+      value.characterStrings = txtStrings
+    }
+  }
+
+  return value
+}
+
+/*
+ *  Splits presentation format text into its character-strings: quoted or bare
+ *  words separated by whitespace. Escapes are \X for a literal X and \DDD for a
+ *  decimal byte value (RFC 1035).
+ */
+func ParseCharacterStrings(text string) ([]string, error) {
+  var characterStrings []string
+  var current []byte
+  var inString bool
+  var quoted bool
+
+  for i := 0; i < len(text); i++ {
+    c := text[i]
+
+    switch {
+    case c == '\\':
+      if i + 3 < len(text) && isDigit(text[i + 1]) && isDigit(text[i + 2]) && isDigit(text[i + 3]) {
+        code, _ := strconv.Atoi(text[i + 1:i + 4])
+        if code > 255 {
+          return nil, errors.New("escaped byte out of range in " + text)
+        }
+        current = append(current, byte(code))
+        i += 3
+      } else if i + 1 < len(text) {
+        current = append(current, text[i + 1])
+        i++
+      } else {
+        return nil, errors.New("dangling escape in " + text)
+      }
+      inString = true
+    case c == '"' && quoted:
+      //closing quote; the string is complete even when empty
+      characterStrings = append(characterStrings, string(current))
+      current, inString, quoted = nil, false, false
+    case c == '"' && !inString:
+      inString, quoted = true, true
+    case (c == ' ' || c == '\t') && !quoted:
+      if inString {
+        characterStrings = append(characterStrings, string(current))
+        current, inString = nil, false
+      }
+    default:
+      current = append(current, c)
+      inString = true
+    }
+  }
+
+  if quoted {
+    return nil, errors.New("unterminated quote in " + text)
+  }
+  if inString {
+    characterStrings = append(characterStrings, string(current))
+  }
+
+  return characterStrings, nil
+}
+func isDigit(c byte) bool {
+  return c >= '0' && c <= '9'
+}
+
+/*
+ *  the character-strings of a TXT value joined back together; how SPF and DKIM
+ *  consumers read them
+ */
+func (value *recordValue) Text() string {
+  return strings.Join(value.characterStrings, "")
+}
+
+/*
+ *  whether parsing found any structure; values without it serialize as just raw
+ */
+func (value *recordValue) IsStructured() bool {
+  return value.caa != nil || value.characterStrings != nil || value.mx != nil || value.srv != nil
+}
+func (value *recordValue) Serialize() string {
+  return marshalJson(value.Output())
+}
@@ -0,0 +1,100 @@
+package main
+import (
+  "encoding/json"
+  "testing"
+
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/route53"
+)
+
+func TestParseCharacterStrings(t *testing.T) {
+  //test cases
+  //  1: several quoted strings, whitespace and commas kept inside them
+  //  2: escaped quotes and decimal escapes
+  //  3: bare words are strings too, and empty quoted strings count
+  //  4: an unterminated quote is an error
+  tc1, err := ParseCharacterStrings("\"v=DKIM1; k=rsa; p=MIIB,\" \"IjAN  Bg\"")
+  if err != nil || len(tc1) != 2 || tc1[0] != "v=DKIM1; k=rsa; p=MIIB," || tc1[1] != "IjAN  Bg" {
+    t.Errorf("tc1 - unexpected strings: %q (error: %v)", tc1, err)
+  }
+
+  tc2, err := ParseCharacterStrings("\"say \\\"hi\\\"\\059 ok\"")
+  if err != nil || len(tc2) != 1 || tc2[0] != "say \"hi\"; ok" {
+    t.Errorf("tc2 - unexpected strings: %q (error: %v)", tc2, err)
+  }
+
+  tc3, err := ParseCharacterStrings("bare \"\"")
+  if err != nil || len(tc3) != 2 || tc3[0] != "bare" || tc3[1] != "" {
+    t.Errorf("tc3 - unexpected strings: %q (error: %v)", tc3, err)
+  }
+
+  if _, err = ParseCharacterStrings("\"open"); err == nil {
+    t.Errorf("tc4 - expected an error for an unterminated quote")
+  }
+}
+
+func TestParseRecordValue(t *testing.T) {
+  //test cases
+  //  1: MX preference and exchange
+  //  2: SRV priority, weight, port and target
+  //  3: CAA flag, tag and unquoted value, however they're spaced out
+  //  4: TXT character-strings join back into the full text
+  //  5: unstructured types and unparseable values are raw only
+  mx := ParseRecordValue("MX", "10 mail.example.com.")
+  if mx.mx == nil || mx.mx.preference != 10 || mx.mx.exchange != "mail.example.com." {
+    t.Errorf("tc1 - unexpected MX value: %+v", mx.mx)
+  }
+
+  srv := ParseRecordValue("SRV", "1 20 443 sip.example.com.")
+  if srv.srv == nil || srv.srv.priority != 1 || srv.srv.weight != 20 || srv.srv.port != 443 || srv.srv.target != "sip.example.com." {
+    t.Errorf("tc2 - unexpected SRV value: %+v", srv.srv)
+  }
+
+  caa := ParseRecordValue("CAA", "0 issuewild \"letsencrypt.org; validationmethods=dns-01\"")
+  if caa.caa == nil || caa.caa.flag != 0 || caa.caa.tag != "issuewild" || caa.caa.value != "letsencrypt.org; validationmethods=dns-01" {
+    t.Errorf("tc3 - unexpected CAA value: %+v", caa.caa)
+  }
+  spaced := ParseRecordValue("CAA", "0  issue\t\"letsencrypt.org\"")
+  if spaced.caa == nil || spaced.caa.tag != "issue" || spaced.caa.value != "letsencrypt.org" {
+    t.Errorf("tc3 - unexpected CAA value: %+v", spaced.caa)
+  }
+
+  txt := ParseRecordValue("TXT", "\"v=spf1 include:a.example.com,\" \"include:b.example.com ~all\"")
+  if len(txt.characterStrings) != 2 || txt.Text() != "v=spf1 include:a.example.com,include:b.example.com ~all" {
+    t.Errorf("tc4 - unexpected TXT value: %q", txt.characterStrings)
+  }
+
+  if ParseRecordValue("A", "192.0.2.1").IsStructured() || ParseRecordValue("MX", "mail.example.com.").IsStructured() {
+    t.Errorf("tc5 - expected raw only values")
+  }
+}
+
+func TestRecordSerializeParsedValues(t *testing.T) {
+  var jsonObject map[string]interface{}
+  zoneRecordset := make(recordset)
+  txt := &route53.ResourceRecordSet{
+    Name: aws.String("example.com."),
+    Type: aws.String("TXT"),
+    TTL: aws.Int64(300),
+    ResourceRecords: []*route53.ResourceRecord{
+      &route53.ResourceRecord{Value: aws.String("\"v=spf1 ip4:192.0.2.1,192.0.2.2 ~all\"")},
+      &route53.ResourceRecord{Value: aws.String("\"google-site-verification=abc,def\"")},
+    },
+  }
+
+  //test cases
+  //  1: values with commas aren't split apart
+  //  2: serialized TXT records are valid JSON with raw and parsed values
+  zoneRecordset.HashRecordsetTypes([]*route53.ResourceRecordSet{txt})
+  values := zoneRecordset["TXT"][0].values
+  if len(values) != 2 || values[0] != "\"v=spf1 ip4:192.0.2.1,192.0.2.2 ~all\"" {
+    t.Errorf("tc1 - unexpected values: %q", values)
+  }
+
+  serialized := zoneRecordset["TXT"][0].Serialize()
+  json.Unmarshal([]byte(serialized), &jsonObject)
+  parsedValues, _ := jsonObject["parsedValues"].([]interface{})
+  if !json.Valid([]byte(serialized)) || len(parsedValues) != 2 || parsedValues[1].(map[string]interface{})["text"] != "google-site-verification=abc,def" {
+    t.Errorf("tc2 - unexpected serialized record: %s", serialized)
+  }
+}
@@ -0,0 +1,189 @@
+package main
+
+import(
+  "fmt"
+  "strings"
+  "time"
+
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/route53domains"
+  "github.com/aws/aws-sdk-go/service/route53domains/route53domainsiface"
+)
+
+//route53 domains is only served out of us-east-1
+const route53DomainsRegion string = "us-east-1"
+
+/*
+ *  registered domain - registrar side view of a domain registered through
+ *  Route53 Domains, along with the hosted zones serving it (if any)
+ */
+type registeredDomain struct {
+  adminPrivacy bool
+  autoRenew bool
+  expiration time.Time
+  hostedZoneIds []string
+  name string
+  nameservers []string
+  registrantPrivacy bool
+  techPrivacy bool
+  transferLock bool
+}
+
+/*
+ *  Creates a route53 domains client from a session (ambient credentials when
+ *  nil), pinned to the only region offering the service.
+ */
+func NewRoute53DomainsClient(sess *session.Session) route53domainsiface.Route53DomainsAPI {
+  if sess == nil {
+    sess = session.Must(session.NewSession())
+  }
+
+  return route53domains.New(sess, aws.NewConfig().WithRegion(route53DomainsRegion))
+}
+
+/*
+ *  A route53 domains client per account the provider scans, built from the same
+ *  sessions (so -profiles, -roles and -accounts apply). Providers that aren't
+ *  route53 use ambient credentials.
+ */
+func RegistrarClients(provider dnsProvider) []route53domainsiface.Route53DomainsAPI {
+  var clients []route53domainsiface.Route53DomainsAPI
+
+  switch p := provider.(type) {
+  case *route53Provider:
+    clients = append(clients, NewRoute53DomainsClient(p.sess))
+  case *multiAccountProvider:
+    for _, account := range p.accounts {
+      clients = append(clients, NewRoute53DomainsClient(account.sess))
+    }
+  default:
+    clients = append(clients, NewRoute53DomainsClient(nil))
+  }
+
+  return clients
+}
+
+/*
+ *  Registered domains from every client, stopping at the first that fails.
+ */
+func GetAccountsRegisteredDomains(clients []route53domainsiface.Route53DomainsAPI) ([]*registeredDomain, error) {
+  var domains []*registeredDomain
+
+  for _, svc := range clients {
+    accountDomains, req := GetRegisteredDomains(svc)
+    if req.err != nil {
+      return domains, fmt.Errorf("calling %s service function %s(): %s", req.serviceName, req.serviceFunction, req.err.Error())
+    }
+    domains = append(domains, accountDomains...)
+  }
+
+  return domains, nil
+}
+
+/*
+ *  Lists every domain registered in the account (all pages) and fills in the
+ *  details the listing leaves out (nameservers and privacy protection). Errors
+ *  are left in the request for the caller to handle.
+ */
+func GetRegisteredDomains(svc route53domainsiface.Route53DomainsAPI) ([]*registeredDomain, *awsRequest) {
+  var args = &route53domains.ListDomainsInput{}
+  var domains []*registeredDomain
+  var moreDomains bool = true
+  var resp *route53domains.ListDomainsOutput
+  req := new(awsRequest)
+
+  //init request metadata
+  req.serviceName = "route53domains"
+  req.serviceFunction = "ListDomains"
+  //handle paginated results
+  for moreDomains {
+    //exec api call and handle error
+    resp, req.err = svc.ListDomains(args)
+    if req.err != nil {
+      return domains, req
+    }
+
+    for _, summary := range resp.Domains {
+      domains = append(domains, &registeredDomain{
+        autoRenew: aws.BoolValue(summary.AutoRenew),
+        expiration: aws.TimeValue(summary.Expiry),
+        name: strings.ToLower(aws.StringValue(summary.DomainName)),
+        transferLock: aws.BoolValue(summary.TransferLock),
+      })
+    }
+
+    moreDomains = resp.NextPageMarker != nil && len(*resp.NextPageMarker) > 0
+    args.Marker = resp.NextPageMarker
+  }
+
+  for _, domain := range domains {
+    var detail *route53domains.GetDomainDetailOutput
+
+    req.serviceFunction = "GetDomainDetail"
+    detail, req.err = svc.GetDomainDetail(&route53domains.GetDomainDetailInput{DomainName: aws.String(domain.name)})
+    if req.err != nil {
+      return domains, req
+    }
+
+    domain.adminPrivacy = aws.BoolValue(detail.AdminPrivacy)
+    domain.registrantPrivacy = aws.BoolValue(detail.RegistrantPrivacy)
+    domain.techPrivacy = aws.BoolValue(detail.TechPrivacy)
+    for _, nameserver := range detail.Nameservers {
+      domain.nameservers = append(domain.nameservers, strings.ToLower(aws.StringValue(nameserver.Name)))
+    }
+
+    //the detail is more authoritative than the summary
+    if detail.ExpirationDate != nil {
+      domain.expiration = *detail.ExpirationDate
+    }
+    for _, status := range detail.StatusList {
+      if aws.StringValue(status) == "clientTransferProhibited" {
+        domain.transferLock = true
+      }
+    }
+  }
+
+  return domains, req
+}
+
+/*
+ *  Matches registered domains up with the hosted zones serving them. More than
+ *  one zone for a domain usually means a stale or shadow hosted zone.
+ */
+func CrossReferenceZones(domains []*registeredDomain, zones []*zone) {
+  zoneIdsByName := make(map[string][]string)
+
+  for _, z := range zones {
+    name := strings.ToLower(z.DomainToString())
+    zoneIdsByName[name] = append(zoneIdsByName[name], z.id)
+  }
+
+  for _, domain := range domains {
+    domain.hostedZoneIds = zoneIdsByName[domain.name]
+  }
+}
+
+/*
+ *  Whole days left before the registration expires (negative once it has).
+ */
+func (d *registeredDomain) DaysUntilExpiration(now time.Time) int {
+  return int(d.expiration.Sub(now).Hours() / 24)
+}
+func (d *registeredDomain) Serialize() string {
+  return marshalJson(d.Output())
+}
+
+/*
+ * Function that serializes an array of registered domain references.
+ * loosely associated with registeredDomain type
+ */
+func SerializeRegisteredDomains(domains []*registeredDomain) string {
+  domainOutputs := make([]*registeredDomainOutput, 0, len(domains))
+
+  for _, domain := range domains {
+    domainOutputs = append(domainOutputs, domain.Output())
+  }
+
+  return serializeDocument("domains", domainOutputs)
+}
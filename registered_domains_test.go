@@ -0,0 +1,128 @@
+package main
+import (
+  "encoding/json"
+  "errors"
+  "strings"
+  "testing"
+  "time"
+
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/route53domains"
+  "github.com/aws/aws-sdk-go/service/route53domains/route53domainsiface"
+)
+
+/*
+ *  route53 domains stand-in; domains are listed one per page
+ */
+type mockRoute53Domains struct {
+  route53domainsiface.Route53DomainsAPI
+  details map[string]*route53domains.GetDomainDetailOutput
+  err error
+  names []string
+}
+func (m *mockRoute53Domains) ListDomains(in *route53domains.ListDomainsInput) (*route53domains.ListDomainsOutput, error) {
+  if m.err != nil {
+    return nil, m.err
+  }
+
+  page := 0
+  for in.Marker != nil && m.names[page] != *in.Marker {
+    page++
+  }
+
+  out := &route53domains.ListDomainsOutput{
+    Domains: []*route53domains.DomainSummary{
+      &route53domains.DomainSummary{
+        DomainName: aws.String(m.names[page]),
+        AutoRenew: m.details[m.names[page]].AutoRenew,
+        Expiry: m.details[m.names[page]].ExpirationDate,
+        TransferLock: aws.Bool(false),
+      },
+    },
+  }
+  if page < len(m.names) - 1 {
+    out.NextPageMarker = aws.String(m.names[page + 1])
+  }
+
+  return out, nil
+}
+func (m *mockRoute53Domains) GetDomainDetail(in *route53domains.GetDomainDetailInput) (*route53domains.GetDomainDetailOutput, error) {
+  return m.details[*in.DomainName], nil
+}
+
+func TestGetRegisteredDomains(t *testing.T) {
+  var jsonObject map[string][]map[string]interface{}
+  expiring := time.Now().Add(10 * 24 * time.Hour + time.Hour)
+  svc := &mockRoute53Domains{
+    names: []string{"example.com", "example.net"},
+    details: map[string]*route53domains.GetDomainDetailOutput{
+      "example.com": &route53domains.GetDomainDetailOutput{
+        AutoRenew: aws.Bool(true),
+        ExpirationDate: aws.Time(time.Now().Add(365 * 24 * time.Hour)),
+        AdminPrivacy: aws.Bool(true),
+        RegistrantPrivacy: aws.Bool(true),
+        TechPrivacy: aws.Bool(true),
+        Nameservers: []*route53domains.Nameserver{
+          &route53domains.Nameserver{Name: aws.String("ns-1.awsdns-01.org")},
+          &route53domains.Nameserver{Name: aws.String("ns-2.awsdns-02.com")},
+        },
+        StatusList: aws.StringSlice([]string{"clientTransferProhibited"}),
+      },
+      "example.net": &route53domains.GetDomainDetailOutput{
+        AutoRenew: aws.Bool(false),
+        ExpirationDate: aws.Time(expiring),
+      },
+    },
+  }
+  zones := []*zone{&zone{id: "Z1", domain: "example", tld: "com"}, &zone{id: "Z2", domain: "example", tld: "com"}, &zone{id: "Z3", domain: "other", tld: "org"}}
+
+  //test cases
+  //  1: domains from every page are listed with their details
+  //  2: transfer lock comes from the status list
+  //  3: hosted zones are matched by name (including duplicates)
+  //  4: days until expiration is reported in the serialized output
+  domains, req := GetRegisteredDomains(svc)
+  if req.err != nil || len(domains) != 2 || len(domains[0].nameservers) != 2 || !domains[0].autoRenew || !domains[0].registrantPrivacy {
+    t.Fatalf("tc1 - unexpected domains: %+v (error: %v)", domains, req.err)
+  }
+
+  if !domains[0].transferLock || domains[1].transferLock {
+    t.Errorf("tc2 - unexpected transfer locks: %t, %t", domains[0].transferLock, domains[1].transferLock)
+  }
+
+  CrossReferenceZones(domains, zones)
+  if len(domains[0].hostedZoneIds) != 2 || len(domains[1].hostedZoneIds) != 0 {
+    t.Errorf("tc3 - unexpected hosted zones: %v, %v", domains[0].hostedZoneIds, domains[1].hostedZoneIds)
+  }
+
+  serialized := SerializeRegisteredDomains(domains)
+  json.Unmarshal([]byte(serialized), &jsonObject)
+  if !json.Valid([]byte(serialized)) || jsonObject["domains"][1]["daysUntilExpiration"] != float64(10) {
+    t.Errorf("tc4 - unexpected serialized domains: %s", serialized)
+  }
+}
+
+func TestGetAccountsRegisteredDomains(t *testing.T) {
+  account := func(name string) *mockRoute53Domains {
+    return &mockRoute53Domains{names: []string{name}, details: map[string]*route53domains.GetDomainDetailOutput{name: &route53domains.GetDomainDetailOutput{}}}
+  }
+  denied := &mockRoute53Domains{err: errors.New("AccessDeniedException: not authorized")}
+
+  //test cases
+  //  1: domains from every account are listed together
+  //  2: a failing call is an error, not an empty inventory
+  //  3: a multi-account provider gets a client per account
+  domains, err := GetAccountsRegisteredDomains([]route53domainsiface.Route53DomainsAPI{account("example.com"), account("example.org")})
+  if err != nil || len(domains) != 2 || domains[1].name != "example.org" {
+    t.Errorf("tc1 - unexpected domains: %+v (error: %v)", domains, err)
+  }
+
+  if _, err = GetAccountsRegisteredDomains([]route53domainsiface.Route53DomainsAPI{account("example.com"), denied}); err == nil || !strings.Contains(err.Error(), "ListDomains") {
+    t.Errorf("tc2 - expected the failed call to be reported: %v", err)
+  }
+
+  provider := &multiAccountProvider{accounts: []*route53Provider{&route53Provider{}, &route53Provider{}}}
+  if clients := RegistrarClients(provider); len(clients) != 2 {
+    t.Errorf("tc3 - expected a client per account, got %d", len(clients))
+  }
+}
@@ -0,0 +1,342 @@
+package main
+
+import(
+  "bytes"
+  "crypto/x509"
+  "crypto/x509/pkix"
+  "encoding/asn1"
+  "errors"
+  "fmt"
+  "io"
+  "io/ioutil"
+  "net/http"
+  "strings"
+  "sync"
+  "time"
+
+  "golang.org/x/crypto/ocsp"
+)
+
+//revocation statuses and where they came from
+const(
+  revocationGood string = "good"
+  revocationRevoked string = "revoked"
+  revocationUnknown string = "unknown"
+  revocationSourceCrl string = "crl"
+  revocationSourceNone string = "none"
+  revocationSourceOcsp string = "ocsp"
+  revocationSourceStapled string = "stapled"
+)
+
+//client for OCSP responders and CRL distribution points
+var revocationClient *http.Client = &http.Client{Timeout: 10 * time.Second}
+
+//largest OCSP response or CRL read
+const maxRevocationResponse int64 = 10 << 20
+
+//whether OCSP responders and CRL distribution points are asked (off with
+//-no-revocation); stapled responses are read either way
+var revocationLookups bool = true
+
+/*
+ *  what a responder or distribution point sent back; sites in a run often share
+ *  an issuer, so each lookup is only made once (concurrent checks wait on it)
+ */
+type revocationFetch struct {
+  content []byte
+  err error
+  once sync.Once
+}
+
+var revocationCache = struct {
+  sync.Mutex
+  fetches map[string]*revocationFetch
+}{fetches: make(map[string]*revocationFetch)}
+
+//CRL entry extension holding the revocation reason (RFC 5280 5.3.1)
+var crlReasonCodeOid = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+//RFC 5280 revocation reasons
+var revocationReasons = map[int]string{
+  ocsp.Unspecified: "unspecified",
+  ocsp.KeyCompromise: "keyCompromise",
+  ocsp.CACompromise: "caCompromise",
+  ocsp.AffiliationChanged: "affiliationChanged",
+  ocsp.Superseded: "superseded",
+  ocsp.CessationOfOperation: "cessationOfOperation",
+  ocsp.CertificateHold: "certificateHold",
+  ocsp.RemoveFromCRL: "removeFromCRL",
+  ocsp.PrivilegeWithdrawn: "privilegeWithdrawn",
+  ocsp.AACompromise: "aaCompromise",
+}
+
+/*
+ *  revocation - whether a leaf certificate has been revoked, according to the
+ *  OCSP response the server stapled, its OCSP responder or its CRL (in that
+ *  order), and whether the server staples at all
+ */
+type revocationReport struct {
+  err error
+  nextUpdate time.Time
+  producedAt time.Time
+  reason string
+  revokedAt time.Time
+  source string
+  stapled bool
+  status string
+  thisUpdate time.Time
+  url string
+}
+
+/*
+ *  Finds out whether leaf has been revoked. A stapled response is used if the
+ *  server sent one (and it's valid for leaf); otherwise, unless revocationLookups
+ *  is off, each OCSP responder in the certificate, then each CRL distribution
+ *  point, is asked until one answers. Nothing can be verified without the
+ *  issuer, so the status is then unknown. Anything that went wrong along the
+ *  way is kept in err, even if a later source answered.
+ */
+func CheckRevocation(leaf *x509.Certificate, issuer *x509.Certificate, stapled []byte, now time.Time) *revocationReport {
+  var errs []string
+  report := &revocationReport{source: revocationSourceNone, stapled: len(stapled) > 0, status: revocationUnknown}
+  answered := false
+
+  //a staple can only be trusted if its signature can be checked against the issuer
+  if report.stapled && issuer == nil {
+    errs = append(errs, "stapled response can't be verified without the issuer certificate")
+  }
+  if report.stapled && issuer != nil {
+    response, err := ocsp.ParseResponseForCert(stapled, leaf, issuer)
+    if err == nil {
+      err = report.fromOcsp(response, revocationSourceStapled, "", now)
+    }
+    if answered = err == nil; !answered {
+      errs = append(errs, "stapled response: " + err.Error())
+    }
+  }
+
+  lookups := !answered && revocationLookups
+  if lookups && issuer == nil && !report.stapled && (len(leaf.OCSPServer) > 0 || len(leaf.CRLDistributionPoints) > 0) {
+    errs = append(errs, "the issuer certificate wasn't sent, so revocation can't be checked")
+  }
+  if lookups && issuer != nil {
+    for _, responder := range leaf.OCSPServer {
+      response, err := QueryOcspResponder(responder, leaf, issuer)
+      if err == nil {
+        err = report.fromOcsp(response, revocationSourceOcsp, responder, now)
+      }
+      if answered = err == nil; answered {
+        break
+      }
+      errs = append(errs, responder + ": " + err.Error())
+    }
+  }
+  if lookups && !answered && issuer != nil {
+    for _, distributionPoint := range leaf.CRLDistributionPoints {
+      err := report.fromCrl(distributionPoint, leaf, issuer, now)
+      if answered = err == nil; answered {
+        break
+      }
+      errs = append(errs, distributionPoint + ": " + err.Error())
+    }
+  }
+
+  if len(errs) > 0 {
+    report.err = errors.New(strings.Join(errs, "; "))
+  }
+
+  return report
+}
+
+/*
+ *  takes the status from an OCSP response; stale responses aren't trusted
+ */
+func (report *revocationReport) fromOcsp(response *ocsp.Response, source string, url string, now time.Time) error {
+  if !response.NextUpdate.IsZero() && now.After(response.NextUpdate) {
+    return fmt.Errorf("response is stale (next update was due %s)", response.NextUpdate.UTC().Format(time.RFC3339))
+  }
+
+  report.source, report.url = source, url
+  report.producedAt, report.thisUpdate, report.nextUpdate = response.ProducedAt, response.ThisUpdate, response.NextUpdate
+  switch response.Status {
+  case ocsp.Good:
+    report.status = revocationGood
+  case ocsp.Revoked:
+    report.status = revocationRevoked
+    report.revokedAt = response.RevokedAt
+    report.reason = revocationReasons[response.RevocationReason]
+  default:
+    report.status = revocationUnknown
+  }
+
+  return nil
+}
+
+/*
+ *  looks leaf's serial up in the CRL at url, which has to be signed by issuer
+ *  and current
+ */
+func (report *revocationReport) fromCrl(url string, leaf *x509.Certificate, issuer *x509.Certificate, now time.Time) error {
+  content, err := cachedRevocationData(url, func() (*http.Request, error) {
+    return http.NewRequest("GET", url, nil)
+  })
+  if err != nil {
+    return err
+  }
+
+  crl, err := x509.ParseCRL(content)
+  if err != nil {
+    return err
+  }
+  if err = issuer.CheckCRLSignature(crl); err != nil {
+    return err
+  }
+  list := crl.TBSCertList
+  if !list.NextUpdate.IsZero() && now.After(list.NextUpdate) {
+    return fmt.Errorf("crl is stale (next update was due %s)", list.NextUpdate.UTC().Format(time.RFC3339))
+  }
+
+  report.source, report.status, report.url = revocationSourceCrl, revocationGood, url
+  report.thisUpdate, report.nextUpdate = list.ThisUpdate, list.NextUpdate
+  for _, revoked := range list.RevokedCertificates {
+    if revoked.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+      report.status = revocationRevoked
+      report.revokedAt = revoked.RevocationTime
+      report.reason = revocationReasons[CrlReasonCode(revoked)]
+      break
+    }
+  }
+
+  return nil
+}
+
+/*
+ *  the reason code extension of a CRL entry (unspecified if there isn't one)
+ */
+func CrlReasonCode(revoked pkix.RevokedCertificate) int {
+  for _, extension := range revoked.Extensions {
+    var reason asn1.Enumerated
+    if extension.Id.Equal(crlReasonCodeOid) {
+      if _, err := asn1.Unmarshal(extension.Value, &reason); err == nil {
+        return int(reason)
+      }
+    }
+  }
+
+  return ocsp.Unspecified
+}
+
+/*
+ *  Asks an OCSP responder (RFC 6960, over http POST) about leaf. The response
+ *  has to be signed by issuer or a responder it delegated to.
+ */
+func QueryOcspResponder(responder string, leaf *x509.Certificate, issuer *x509.Certificate) (*ocsp.Response, error) {
+  request, err := ocsp.CreateRequest(leaf, issuer, nil)
+  if err != nil {
+    return nil, err
+  }
+
+  //the request names the issuer and serial, so it keys the cache along with the responder
+  content, err := cachedRevocationData(responder + " " + string(request), func() (*http.Request, error) {
+    httpRequest, err := http.NewRequest("POST", responder, bytes.NewReader(request))
+    if err != nil {
+      return nil, err
+    }
+    httpRequest.Header.Set("Content-Type", "application/ocsp-request")
+    httpRequest.Header.Set("Accept", "application/ocsp-response")
+    return httpRequest, nil
+  })
+  if err != nil {
+    return nil, err
+  }
+
+  return ocsp.ParseResponseForCert(content, leaf, issuer)
+}
+
+/*
+ *  the response to a lookup, made the first time key is asked for in this run
+ */
+func cachedRevocationData(key string, newRequest func() (*http.Request, error)) ([]byte, error) {
+  revocationCache.Lock()
+  fetch, found := revocationCache.fetches[key]
+  if !found {
+    fetch = new(revocationFetch)
+    revocationCache.fetches[key] = fetch
+  }
+  revocationCache.Unlock()
+
+  fetch.once.Do(func() {
+    request, err := newRequest()
+    if err != nil {
+      fetch.err = err
+      return
+    }
+    fetch.content, fetch.err = fetchRevocationData(request)
+  })
+
+  return fetch.content, fetch.err
+}
+func fetchRevocationData(request *http.Request) ([]byte, error) {
+  response, err := revocationClient.Do(request)
+  if err != nil {
+    return nil, err
+  }
+  defer response.Body.Close()
+
+  if response.StatusCode != http.StatusOK {
+    return nil, fmt.Errorf("http status %d", response.StatusCode)
+  }
+
+  return ioutil.ReadAll(io.LimitReader(response.Body, maxRevocationResponse))
+}
+
+/*
+ *  The certificate that issued leaf: from the verified chain if there is one,
+ *  otherwise whichever presented certificate signed it (nil if none did).
+ */
+func IssuerOf(leaf *x509.Certificate, chain *chainReport) *x509.Certificate {
+  if chain == nil {
+    return nil
+  }
+
+  for _, verified := range chain.verified {
+    if len(verified) > 1 && verified[0].Equal(leaf) {
+      return verified[1]
+    }
+  }
+  for _, cert := range chain.presented {
+    if !cert.Equal(leaf) && leaf.CheckSignatureFrom(cert) == nil {
+      return cert
+    }
+  }
+
+  return nil
+}
+
+func (report *revocationReport) Output() *revocationOutput {
+  output := &revocationOutput{
+    NextUpdate: optionalTime(report.nextUpdate),
+    ProducedAt: optionalTime(report.producedAt),
+    Reason: report.reason,
+    RevokedAt: optionalTime(report.revokedAt),
+    Source: report.source,
+    Stapled: report.stapled,
+    Status: report.status,
+    ThisUpdate: optionalTime(report.thisUpdate),
+    Url: report.url,
+  }
+
+  if report.err != nil {
+    output.ErrorMessage = report.err.Error()
+  }
+
+  return output
+}
+func optionalTime(t time.Time) *time.Time {
+  if t.IsZero() {
+    return nil
+  }
+
+  utc := t.UTC()
+  return &utc
+}
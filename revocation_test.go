@@ -0,0 +1,185 @@
+package main
+import (
+  "crypto/rand"
+  "crypto/x509"
+  "crypto/x509/pkix"
+  "encoding/asn1"
+  "io/ioutil"
+  "math/big"
+  "net/http"
+  "net/http/httptest"
+  "strings"
+  "sync/atomic"
+  "testing"
+  "time"
+
+  "golang.org/x/crypto/ocsp"
+)
+
+/*
+ *  helper for leaves naming an OCSP responder and a CRL distribution point
+ *  (either may be empty)
+ */
+func createRevocableLeaf(t *testing.T, ca *testCertificate, serial int64, ocspServer string, crlUrl string) *x509.Certificate {
+  return createTestCertificateWith(t, "www.example.com", ca, false, time.Now().Add(-time.Hour), time.Now().Add(24 * time.Hour), testCertificateOptions{
+    crlUrl: crlUrl,
+    dnsNames: []string{"www.example.com"},
+    ocspServer: ocspServer,
+    serial: serial,
+  }).cert
+}
+
+/*
+ *  helper signing an OCSP response for leaf
+ */
+func createOcspResponse(t *testing.T, ca *testCertificate, leaf *x509.Certificate, status int, nextUpdate time.Time) []byte {
+  template := ocsp.Response{
+    NextUpdate: nextUpdate,
+    SerialNumber: leaf.SerialNumber,
+    Status: status,
+    ThisUpdate: time.Now().Add(-time.Hour),
+  }
+  if status == ocsp.Revoked {
+    template.RevokedAt = time.Now().Add(-2 * time.Hour)
+    template.RevocationReason = ocsp.KeyCompromise
+  }
+
+  response, err := ocsp.CreateResponse(ca.cert, ca.cert, template, ca.key)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  return response
+}
+
+func TestCheckRevocation(t *testing.T) {
+  now := time.Now()
+  //the CA also signs the CRL
+  ca := createTestCertificateWith(t, "Revocation Test CA", nil, true, now.Add(-time.Hour), now.Add(24 * time.Hour), testCertificateOptions{
+    keyUsage: x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+  })
+  responderStatus := ocsp.Good
+  var responderRequests int32
+  responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    atomic.AddInt32(&responderRequests, 1)
+    body, _ := ioutil.ReadAll(r.Body)
+    request, err := ocsp.ParseRequest(body)
+    if err != nil || r.Header.Get("Content-Type") != "application/ocsp-request" {
+      w.WriteHeader(http.StatusBadRequest)
+      return
+    }
+    w.Write(createOcspResponse(t, ca, &x509.Certificate{SerialNumber: request.SerialNumber}, responderStatus, now.Add(time.Hour)))
+  }))
+  defer responder.Close()
+  superseded, _ := asn1.Marshal(asn1.Enumerated(ocsp.Superseded))
+  crl, err := ca.cert.CreateCRL(rand.Reader, ca.key, []pkix.RevokedCertificate{{
+    Extensions: []pkix.Extension{{Id: crlReasonCodeOid, Value: superseded}},
+    RevocationTime: now.Add(-time.Hour),
+    SerialNumber: big.NewInt(66),
+  }}, now.Add(-time.Hour), now.Add(time.Hour))
+  if err != nil {
+    t.Fatal(err)
+  }
+  distributionPoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    w.Write(crl)
+  }))
+  defer distributionPoint.Close()
+  broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    w.WriteHeader(http.StatusInternalServerError)
+  }))
+  defer broken.Close()
+  defer func(lookups bool) { revocationLookups = lookups }(revocationLookups)
+
+  //test cases
+  //  1: a stapled good response is used as is
+  //  2: without a staple, the OCSP responder is asked (revoked, with a reason)
+  //  3: a stale staple falls back to the responder, and says why
+  //  4: without a responder, the CRL lists the certificate as revoked
+  //  5: a certificate missing from the CRL is good
+  //  6: a responder that fails falls back to the CRL
+  //  7: nowhere to ask is unknown
+  //  8: a failing responder and no CRL is unknown, with the error
+  //  9: a staple without the issuer to verify it is unknown, not good
+  // 10: the responder is only asked once about a certificate in a run
+  // 11: with lookups turned off (-no-revocation), nothing is asked
+  leaf := createRevocableLeaf(t, ca, 65, responder.URL, "")
+  tc1 := CheckRevocation(leaf, ca.cert, createOcspResponse(t, ca, leaf, ocsp.Good, now.Add(time.Hour)), now).Output()
+  if tc1.Status != "good" || tc1.Source != "stapled" || !tc1.Stapled || tc1.ThisUpdate == nil || tc1.NextUpdate == nil || len(tc1.ErrorMessage) != 0 {
+    t.Errorf("tc1 - expected a good stapled response: %+v", tc1)
+  }
+
+  responderStatus = ocsp.Revoked
+  tc2 := CheckRevocation(leaf, ca.cert, nil, now).Output()
+  if tc2.Status != "revoked" || tc2.Source != "ocsp" || tc2.Stapled || tc2.Url != responder.URL || tc2.RevokedAt == nil || tc2.Reason != "keyCompromise" {
+    t.Errorf("tc2 - expected the responder to report a revocation: %+v", tc2)
+  }
+
+  tc3 := CheckRevocation(leaf, ca.cert, createOcspResponse(t, ca, leaf, ocsp.Good, now.Add(-time.Minute)), now).Output()
+  if tc3.Status != "revoked" || tc3.Source != "ocsp" || !tc3.Stapled || !strings.Contains(tc3.ErrorMessage, "stale") {
+    t.Errorf("tc3 - expected the stale staple to be passed over: %+v", tc3)
+  }
+
+  tc4 := CheckRevocation(createRevocableLeaf(t, ca, 66, "", distributionPoint.URL), ca.cert, nil, now).Output()
+  if tc4.Status != "revoked" || tc4.Source != "crl" || tc4.Reason != "superseded" || tc4.RevokedAt == nil {
+    t.Errorf("tc4 - expected the CRL to list the certificate: %+v", tc4)
+  }
+
+  tc5 := CheckRevocation(createRevocableLeaf(t, ca, 67, "", distributionPoint.URL), ca.cert, nil, now).Output()
+  if tc5.Status != "good" || tc5.Source != "crl" || tc5.RevokedAt != nil {
+    t.Errorf("tc5 - expected a certificate missing from the CRL to be good: %+v", tc5)
+  }
+
+  tc6 := CheckRevocation(createRevocableLeaf(t, ca, 68, broken.URL, distributionPoint.URL), ca.cert, nil, now).Output()
+  if tc6.Status != "good" || tc6.Source != "crl" || !strings.Contains(tc6.ErrorMessage, "http status 500") {
+    t.Errorf("tc6 - expected a fallback to the CRL: %+v", tc6)
+  }
+
+  tc7 := CheckRevocation(createRevocableLeaf(t, ca, 69, "", ""), ca.cert, nil, now).Output()
+  if tc7.Status != "unknown" || tc7.Source != "none" || len(tc7.ErrorMessage) != 0 {
+    t.Errorf("tc7 - expected an unknown status: %+v", tc7)
+  }
+
+  tc8 := CheckRevocation(createRevocableLeaf(t, ca, 70, broken.URL, ""), ca.cert, nil, now).Output()
+  if tc8.Status != "unknown" || tc8.Source != "none" || !strings.Contains(tc8.ErrorMessage, broken.URL) {
+    t.Errorf("tc8 - expected the responder's failure to be reported: %+v", tc8)
+  }
+
+  tc9 := CheckRevocation(leaf, nil, createOcspResponse(t, ca, leaf, ocsp.Good, now.Add(time.Hour)), now).Output()
+  if tc9.Status != "unknown" || tc9.Source != "none" || !tc9.Stapled || !strings.Contains(tc9.ErrorMessage, "can't be verified") {
+    t.Errorf("tc9 - expected an unverifiable staple to be unknown: %+v", tc9)
+  }
+
+  requests := atomic.LoadInt32(&responderRequests)
+  tc10 := CheckRevocation(leaf, ca.cert, nil, now).Output()
+  if tc10.Status != "revoked" || tc10.Source != "ocsp" || atomic.LoadInt32(&responderRequests) != requests {
+    t.Errorf("tc10 - expected the cached response (%d requests before, %d after): %+v", requests, atomic.LoadInt32(&responderRequests), tc10)
+  }
+
+  revocationLookups = false
+  tc11 := CheckRevocation(createRevocableLeaf(t, ca, 71, responder.URL, distributionPoint.URL), ca.cert, nil, now).Output()
+  if tc11.Status != "unknown" || tc11.Source != "none" || len(tc11.ErrorMessage) != 0 || atomic.LoadInt32(&responderRequests) != requests {
+    t.Errorf("tc11 - expected no lookups: %+v", tc11)
+  }
+}
+
+func TestIssuerOf(t *testing.T) {
+  root, intermediate, leaf := createTestChain(t)
+  roots := x509.NewCertPool()
+  roots.AddCert(root.cert)
+
+  //test cases
+  //  1: the verified chain gives the issuer
+  //  2: an unverified chain still finds the presented certificate that signed it
+  //  3: no issuer among what was presented
+  if issuer := IssuerOf(leaf.cert, AnalyzeChain([]*x509.Certificate{leaf.cert, intermediate.cert}, roots, time.Now())); issuer == nil || !issuer.Equal(intermediate.cert) {
+    t.Errorf("tc1 - expected the intermediate")
+  }
+
+  if issuer := IssuerOf(leaf.cert, AnalyzeChain([]*x509.Certificate{intermediate.cert, leaf.cert}, x509.NewCertPool(), time.Now())); issuer == nil || !issuer.Equal(intermediate.cert) {
+    t.Errorf("tc2 - expected the intermediate")
+  }
+
+  if issuer := IssuerOf(leaf.cert, AnalyzeChain([]*x509.Certificate{leaf.cert}, x509.NewCertPool(), time.Now())); issuer != nil {
+    t.Errorf("tc3 - expected no issuer, got %s", issuer.Subject.String())
+  }
+}
@@ -0,0 +1,191 @@
+package main
+
+import(
+  "strconv"
+  "strings"
+
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/aws/session"
+  "github.com/aws/aws-sdk-go/service/route53"
+  "github.com/aws/aws-sdk-go/service/route53/route53iface"
+)
+
+/*
+ *  route53 provider - hosted zones and recordsets from AWS Route53
+ *  pageSize (MaxItems) of zero leaves paging up to the service (100 zones, 300 records)
+ *  zones are tagged with the account when one is set (multi-account scans)
+ */
+type route53Provider struct {
+  account *awsAccount
+  pageSize int64
+  sess *session.Session
+  svc route53iface.Route53API
+}
+
+/*
+ *  Creates a route53 backed provider. When no client is given, one is built from
+ *  ambient credentials (environment, shared config, instance role, etc.). The
+ *  session is kept so other aws services (route53 domains) use the same account.
+ */
+func NewRoute53Provider(svc route53iface.Route53API) *route53Provider {
+  var sess *session.Session
+  if svc == nil {
+    sess = session.Must(session.NewSession())
+    svc = route53.New(sess)
+  }
+
+  return &route53Provider{sess: sess, svc: svc}
+}
+func (p *route53Provider) GetZones() ([]*zone, error) {
+  zones, req := GetHostedZones(p.svc, p.listHostedZonesInput())
+  for _, z := range zones {
+    p.tagZone(z)
+  }
+
+  return zones, req.err
+}
+func (p *route53Provider) EachZone(fn func(*zone) bool) error {
+  req := EachHostedZone(p.svc, p.listHostedZonesInput(), func(z *zone) bool {
+    p.tagZone(z)
+    return fn(z)
+  })
+
+  return req.err
+}
+func (p *route53Provider) GetRecordsets(zoneId string) (*recordset, error) {
+  zoneRecordset, req := GetRecordsetsForZone(p.svc, zoneId, p.pageSize)
+
+  return zoneRecordset, req.err
+}
+func (p *route53Provider) AttachHealthChecks(zoneId string, rset *recordset) {
+  AttachHealthChecks(p.svc, rset)
+}
+func (p *route53Provider) Metadata() *providerMetadata {
+  return &providerMetadata{
+    description: "AWS Route53 hosted zones",
+    name: "route53",
+  }
+}
+func (p *route53Provider) tagZone(z *zone) {
+  if p.account != nil {
+    z.accountAlias = p.account.alias
+    z.accountId = p.account.id
+  }
+}
+func (p *route53Provider) listHostedZonesInput() *route53.ListHostedZonesInput {
+  args := &route53.ListHostedZonesInput{}
+  if p.pageSize > 0 {
+    args.SetMaxItems(strconv.FormatInt(p.pageSize, 10))
+  }
+
+  return args
+}
+
+/*
+ *  Collects every hosted zone in the account (all pages).
+ */
+func GetHostedZones(svc route53iface.Route53API, args *route53.ListHostedZonesInput) ([]*zone, *awsRequest) {
+  var zones []*zone
+
+  req := EachHostedZone(svc, args, func(z *zone) bool {
+    zones = append(zones, z)
+    return true
+  })
+
+  return zones, req
+}
+
+/*
+ *  Streams hosted zones to fn one page at a time, following NextMarker until the
+ *  listing is no longer truncated or fn returns false. Only a single page of
+ *  zones is held in memory.
+ */
+func EachHostedZone(svc route53iface.Route53API, args *route53.ListHostedZonesInput, fn func(*zone) bool) *awsRequest {
+  var moreZones bool = true
+  var resp *route53.ListHostedZonesOutput
+  req := new(awsRequest)
+
+  //init request metadata
+  req.serviceName = "route53"
+  req.serviceFunction = "ListHostedZones"
+  //the error goes back to the caller, which may have output to close off first
+  req.fatalOnError = false
+  //handle paginated results
+  for moreZones {
+    //exec api call and handle error
+    resp, req.err = svc.ListHostedZones(args)
+    req.HandleServiceRequestError()
+    if req.err != nil {
+      return req
+    }
+
+    for _, currentZone := range resp.HostedZones {
+      z := new(zone)
+
+      //only the last part of the zone id is relevant
+      z.id = strings.Split(*currentZone.Id,"/")[2]
+      z.SetName(*currentZone.Name)
+      z.recordCount = currentZone.ResourceRecordSetCount
+      if currentZone.Config != nil {
+        z.comment = aws.StringValue(currentZone.Config.Comment)
+        z.private = aws.BoolValue(currentZone.Config.PrivateZone)
+      }
+      if !fn(z) {
+        return req
+      }
+    }
+
+    moreZones = resp.IsTruncated != nil && *resp.IsTruncated
+    if moreZones {
+      args.SetMarker(*resp.NextMarker)
+    }
+  }
+
+  return req
+}
+
+func GetRecordsetsForZone(svc route53iface.Route53API, zoneId string, pageSize int64) (*recordset, *awsRequest) {
+  var args = &route53.ListResourceRecordSetsInput{
+    HostedZoneId: aws.String(zoneId),
+    //using the following doesn't work... we'll just filter in memory (*sigh*)
+    //StartRecordName: aws.String("*"),
+    //StartRecordType: aws.String("A"),
+  }
+  var moreRecords bool = true
+  var resp *route53.ListResourceRecordSetsOutput
+  req := new(awsRequest)
+  zoneRecordset := make(recordset)
+
+  if pageSize > 0 {
+    args.SetMaxItems(strconv.FormatInt(pageSize, 10))
+  }
+
+  //init request metadata
+  req.serviceName = "route53"
+  req.serviceFunction = "ListResourceRecordSets"
+  req.fatalOnError = true
+  //handle paginated results
+  for moreRecords {
+    //exec api call and handle error
+    resp, req.err = svc.ListResourceRecordSets(args)
+    req.HandleServiceRequestError()
+    if req.err != nil {
+      return &zoneRecordset, req
+    }
+
+    //in-memory filter
+    zoneRecordset.HashRecordsetTypes(resp.ResourceRecordSets)
+    if *resp.IsTruncated {
+      //the next page starts at a name, type and (for routing policies) set identifier
+      args.StartRecordName = resp.NextRecordName
+      args.StartRecordType = resp.NextRecordType
+      args.StartRecordIdentifier = resp.NextRecordIdentifier
+      moreRecords = true
+    } else {
+      moreRecords = false
+    }
+  }
+
+  //don't pass what could be a massive amount of data, just the reference
+  return &zoneRecordset, req
+}
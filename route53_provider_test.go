@@ -0,0 +1,195 @@
+package main
+import (
+  "encoding/json"
+  "errors"
+  "fmt"
+  "strconv"
+  "strings"
+  "testing"
+
+  "github.com/aws/aws-sdk-go/aws"
+  "github.com/aws/aws-sdk-go/service/route53"
+  "github.com/aws/aws-sdk-go/service/route53/route53iface"
+)
+
+/*
+ *  route53 client stand-in; only the calls we make are implemented, everything
+ *  else panics via the embedded (nil) interface
+ */
+type mockRoute53 struct {
+  route53iface.Route53API
+  checkerReports map[string]string
+  healthCheckCalls int
+  hostedZones []*route53.HostedZone
+  //returned instead of any zone page after the first
+  laterPageError error
+  recordPages [][]*route53.ResourceRecordSet
+  zoneCalls int
+}
+func (m *mockRoute53) ListHostedZones(in *route53.ListHostedZonesInput) (*route53.ListHostedZonesOutput, error) {
+  //the marker is the index of the first zone in the page
+  start, end := 0, len(m.hostedZones)
+  if in.Marker != nil {
+    if m.laterPageError != nil {
+      return nil, m.laterPageError
+    }
+    start, _ = strconv.Atoi(*in.Marker)
+  }
+  if in.MaxItems != nil {
+    pageSize, _ := strconv.Atoi(*in.MaxItems)
+    if start + pageSize < end {
+      end = start + pageSize
+    }
+  }
+
+  m.zoneCalls++
+  out := &route53.ListHostedZonesOutput{
+    HostedZones: m.hostedZones[start:end],
+    IsTruncated: aws.Bool(end < len(m.hostedZones)),
+  }
+  if *out.IsTruncated {
+    out.NextMarker = aws.String(strconv.Itoa(end))
+  }
+
+  return out, nil
+}
+func (m *mockRoute53) ListResourceRecordSets(in *route53.ListResourceRecordSetsInput) (*route53.ListResourceRecordSetsOutput, error) {
+  //page index is carried in the start record name
+  page := 0
+  if in.StartRecordName != nil {
+    page = len(*in.StartRecordName)
+  }
+
+  out := &route53.ListResourceRecordSetsOutput{
+    ResourceRecordSets: m.recordPages[page],
+    IsTruncated: aws.Bool(page < len(m.recordPages) - 1),
+  }
+  if *out.IsTruncated {
+    out.NextRecordName = aws.String(string(make([]byte, page + 1)))
+  }
+
+  return out, nil
+}
+
+func createRoute53Recordset(name string, recordType string, value string) *route53.ResourceRecordSet {
+  return &route53.ResourceRecordSet{
+    Name: aws.String(name),
+    Type: aws.String(recordType),
+    ResourceRecords: []*route53.ResourceRecord{
+      &route53.ResourceRecord{Value: aws.String(value)},
+    },
+  }
+}
+
+func TestRoute53ProviderGetZones(t *testing.T) {
+  var provider dnsProvider = NewRoute53Provider(&mockRoute53{
+    hostedZones: []*route53.HostedZone{
+      &route53.HostedZone{
+        Id: aws.String("/hostedzone/Z1EXAMPLE"),
+        Name: aws.String("example.com."),
+        ResourceRecordSetCount: aws.Int64(7),
+      },
+    },
+  })
+
+  //test cases
+  //  1: zones are returned without error
+  //  2: zone id, domain and record count are pulled from the hosted zone
+  zones, err := provider.GetZones()
+  if err != nil || len(zones) != 1 {
+    t.Fatalf("tc1 - expected a single zone, received: %d (error: %v)", len(zones), err)
+  }
+
+  if zones[0].id != "Z1EXAMPLE" || zones[0].DomainToString() != "example.com" || zones[0].recordCount == nil || *zones[0].recordCount != 7 {
+    t.Errorf("tc2 - unexpected zone: %+v", zones[0])
+  }
+}
+
+func TestRoute53ProviderGetRecordsets(t *testing.T) {
+  var provider dnsProvider = NewRoute53Provider(&mockRoute53{
+    recordPages: [][]*route53.ResourceRecordSet{
+      []*route53.ResourceRecordSet{createRoute53Recordset("www.example.com.", "A", "192.0.2.1")},
+      []*route53.ResourceRecordSet{createRoute53Recordset("api.example.com.", "A", "192.0.2.2")},
+    },
+  })
+
+  //test cases
+  //  1: records from every page are collected
+  //  2: provider metadata names route53
+  zoneRecords, err := provider.GetRecordsets("Z1EXAMPLE")
+  if err != nil || len((*zoneRecords)["A"]) != 2 {
+    t.Errorf("tc1 - expected 2 A records across pages: %+v (error: %v)", zoneRecords, err)
+  }
+
+  if provider.Metadata().name != "route53" {
+    t.Errorf("tc2 - expected provider name \"route53\", received: \"%s\"", provider.Metadata().name)
+  }
+}
+
+func createHostedZones(count int) []*route53.HostedZone {
+  hostedZones := make([]*route53.HostedZone, count)
+  for i := 0; i < count; i++ {
+    hostedZones[i] = &route53.HostedZone{
+      Id: aws.String(fmt.Sprintf("/hostedzone/Z%d", i)),
+      Name: aws.String(fmt.Sprintf("domain%d.com.", i)),
+      ResourceRecordSetCount: aws.Int64(2),
+    }
+  }
+
+  return hostedZones
+}
+
+func TestRoute53ProviderZonePagination(t *testing.T) {
+  svc := &mockRoute53{hostedZones: createHostedZones(250)}
+  provider := NewRoute53Provider(svc)
+  provider.pageSize = 100
+
+  //test cases
+  //  1: every page of zones is listed (250 zones over 3 pages)
+  //  2: streaming stops requesting pages once the caller is done
+  zones, err := provider.GetZones()
+  if err != nil || len(zones) != 250 || zones[249].id != "Z249" || svc.zoneCalls != 3 {
+    t.Errorf("tc1 - expected 250 zones from 3 calls, received %d zones from %d calls (error: %v)", len(zones), svc.zoneCalls, err)
+  }
+
+  svc.zoneCalls = 0
+  streamed := 0
+  EachZone(provider, func(z *zone) bool {
+    streamed++
+    return streamed < 150
+  })
+  if streamed != 150 || svc.zoneCalls != 2 {
+    t.Errorf("tc2 - expected to stop after 150 zones and 2 calls, received %d zones from %d calls", streamed, svc.zoneCalls)
+  }
+}
+
+func TestWriteZones(t *testing.T) {
+  var jsonObject map[string][]interface{}
+  var output strings.Builder
+  provider := NewRoute53Provider(&mockRoute53{hostedZones: createHostedZones(3)})
+  provider.pageSize = 2
+
+  //test cases
+  //  1: streamed output is valid json
+  //  2: every zone is written
+  //  3: a provider failing part way through still leaves valid json
+  if err := WriteZones(&output, provider, new(jsonFormatter)); err != nil {
+    t.Fatalf("unexpected error: %s", err.Error())
+  }
+
+  if !json.Valid([]byte(output.String())) {
+    t.Errorf("tc1 - invalid JSON: %s", output.String())
+  }
+
+  json.Unmarshal([]byte(output.String()), &jsonObject)
+  if len(jsonObject["zones"]) != 3 {
+    t.Errorf("tc2 - expected 3 zones: %s", output.String())
+  }
+
+  output.Reset()
+  failing := NewRoute53Provider(&mockRoute53{hostedZones: createHostedZones(3), laterPageError: errors.New("throttled")})
+  failing.pageSize = 2
+  if err := WriteZones(&output, failing, new(jsonFormatter)); err == nil || !json.Valid([]byte(output.String())) {
+    t.Errorf("tc3 - expected an error and valid JSON: %s (error: %v)", output.String(), err)
+  }
+}
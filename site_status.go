@@ -0,0 +1,111 @@
+package main
+
+import(
+  "fmt"
+  "time"
+)
+
+//site statuses, from best to worst (see statusSeverity)
+const(
+  statusOk string = "ok"
+  statusUnknown string = "unknown"
+  statusWarning string = "warning"
+  statusCritical string = "critical"
+)
+
+//how worst is picked: certificate problems outrank sites that couldn't be checked
+var statusSeverity = map[string]int{statusOk: 0, statusUnknown: 1, statusWarning: 2, statusCritical: 3}
+
+//exit codes for each status, the way nagios plugins report them
+var statusExitCodes = map[string]int{statusOk: 0, statusWarning: 1, statusCritical: 2, statusUnknown: 3}
+
+/*
+ *  days left on a certificate at which a site becomes a warning, or critical
+ */
+type expiryThresholds struct {
+  criticalDays int
+  warningDays int
+}
+
+//thresholds site results are judged by (-warn-days, -crit-days)
+var siteThresholds = expiryThresholds{criticalDays: 7, warningDays: 30}
+
+func NewExpiryThresholds(warningDays int, criticalDays int) (expiryThresholds, error) {
+  if criticalDays < 0 || warningDays < 0 {
+    return expiryThresholds{}, fmt.Errorf("expiry thresholds can't be negative (warning: %d, critical: %d)", warningDays, criticalDays)
+  }
+  if criticalDays > warningDays {
+    return expiryThresholds{}, fmt.Errorf("the critical threshold (%d days) has to be at or below the warning threshold (%d days)", criticalDays, warningDays)
+  }
+
+  return expiryThresholds{criticalDays: criticalDays, warningDays: warningDays}, nil
+}
+
+/*
+ *  Whole days left before a certificate expires (negative once it has).
+ */
+func DaysRemaining(expiration time.Time, now time.Time) int {
+  return int(expiration.Sub(now).Hours() / 24)
+}
+
+/*
+ *  Status of a certificate expiring at expiration: critical once it's expired or
+ *  within criticalDays, a warning within warningDays, ok otherwise.
+ */
+func (thresholds expiryThresholds) Status(expiration time.Time, now time.Time) string {
+  days := DaysRemaining(expiration, now)
+
+  switch {
+  case !now.Before(expiration) || days <= thresholds.criticalDays:
+    return statusCritical
+  case days <= thresholds.warningDays:
+    return statusWarning
+  }
+
+  return statusOk
+}
+
+/*
+ *  Expiry status of a site check; sites that failed or weren't served over
+ *  https have no certificate to judge, so they're unknown.
+ */
+func (thresholds expiryThresholds) ExpiryStatus(res *requestResult, now time.Time) string {
+  if res.callError != nil || !res.responseEncrypted || res.certExpiration.IsZero() {
+    return statusUnknown
+  }
+
+  return thresholds.Status(res.certExpiration, now)
+}
+
+/*
+ *  Overall status of a site check: its expiry status, unless the certificate is
+ *  revoked or clients won't accept it (only reached through the insecure
+ *  fallback, or failing verification), which is critical however long it has
+ *  left.
+ */
+func (thresholds expiryThresholds) SiteStatus(res *requestResult, now time.Time) string {
+  status := thresholds.ExpiryStatus(res, now)
+  if status == statusUnknown {
+    return status
+  }
+
+  if res.revocation != nil && res.revocation.status == revocationRevoked {
+    return statusCritical
+  }
+  if res.insecureFallback || (res.verification != nil && (res.verification.insecureFallback || len(res.verification.failures) > 0)) {
+    return statusCritical
+  }
+
+  return status
+}
+
+/*
+ *  the worse of two statuses
+ */
+func WorstStatus(current string, status string) string {
+  if statusSeverity[status] > statusSeverity[current] {
+    return status
+  }
+
+  return current
+}
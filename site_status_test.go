@@ -0,0 +1,125 @@
+package main
+import (
+  "errors"
+  "strings"
+  "testing"
+  "time"
+)
+
+func TestNewExpiryThresholds(t *testing.T) {
+  //test cases
+  //  1: critical at or below warning is accepted
+  //  2: critical above warning is refused
+  //  3: negative thresholds are refused
+  if thresholds, err := NewExpiryThresholds(30, 30); err != nil || thresholds.warningDays != 30 || thresholds.criticalDays != 30 {
+    t.Errorf("tc1 - expected thresholds of 30 days: %+v (error: %v)", thresholds, err)
+  }
+
+  if _, err := NewExpiryThresholds(7, 30); err == nil {
+    t.Errorf("tc2 - expected an error for critical above warning")
+  }
+
+  if _, err := NewExpiryThresholds(30, -1); err == nil {
+    t.Errorf("tc3 - expected an error for a negative threshold")
+  }
+}
+
+func TestExpiryThresholdsStatus(t *testing.T) {
+  now := time.Now()
+  thresholds := expiryThresholds{criticalDays: 7, warningDays: 30}
+
+  //test cases
+  //  1: beyond the warning threshold is ok
+  //  2: within the warning threshold is a warning
+  //  3: within the critical threshold is critical
+  //  4: expired (even by less than a day) is critical
+  //  5: days remaining go negative once expired
+  if status := thresholds.Status(now.Add(31 * 24 * time.Hour + time.Hour), now); status != statusOk {
+    t.Errorf("tc1 - expected ok, got %s", status)
+  }
+
+  if status := thresholds.Status(now.Add(30 * 24 * time.Hour), now); status != statusWarning {
+    t.Errorf("tc2 - expected a warning, got %s", status)
+  }
+
+  if status := thresholds.Status(now.Add(3 * 24 * time.Hour), now); status != statusCritical {
+    t.Errorf("tc3 - expected critical, got %s", status)
+  }
+
+  if status := thresholds.Status(now.Add(-time.Hour), now); status != statusCritical {
+    t.Errorf("tc4 - expected critical, got %s", status)
+  }
+
+  if days := DaysRemaining(now.Add(-49 * time.Hour), now); days != -2 {
+    t.Errorf("tc5 - expected -2 days, got %d", days)
+  }
+}
+
+func TestCheckSitesExitCode(t *testing.T) {
+  var output strings.Builder
+  now := time.Now()
+  expirations := map[string]time.Time{
+    "ok.example.com": now.Add(90 * 24 * time.Hour),
+    "soon.example.com": now.Add(20 * 24 * time.Hour),
+    "expired.example.com": now.Add(-24 * time.Hour),
+  }
+  check := func(site string) *requestResult {
+    if site == "down.example.com" {
+      return &requestResult{site: site, callError: errors.New("connection refused")}
+    }
+    if site == "revoked.example.com" {
+      return &requestResult{site: site, responseEncrypted: true, certExpiration: now.Add(90 * 24 * time.Hour), revocation: &revocationReport{status: revocationRevoked}}
+    }
+    return &requestResult{site: site, responseEncrypted: true, certExpiration: expirations[site]}
+  }
+  defer func(thresholds expiryThresholds) { siteThresholds = thresholds }(siteThresholds)
+  siteThresholds = expiryThresholds{criticalDays: 7, warningDays: 30}
+
+  //test cases
+  //  1: every site ok exits 0
+  //  2: a site that couldn't be checked is unknown (3)
+  //  3: a warning outranks unknown (1)
+  //  4: critical outranks everything (2)
+  //  5: the site status drives the exit code, not just expiry
+  //  6: each site carries its own status and days remaining
+  //  7: a revoked certificate is critical however long it has left, but its
+  //     expiry status only goes by the thresholds
+  //  8: so is one that failed verification, or was only reached insecurely
+  for i, tc := range []struct {
+    sites []string
+    status string
+    exitCode int
+  }{
+    {[]string{"ok.example.com"}, statusOk, 0},
+    {[]string{"ok.example.com", "down.example.com"}, statusUnknown, 3},
+    {[]string{"soon.example.com", "down.example.com"}, statusWarning, 1},
+    {[]string{"soon.example.com", "expired.example.com", "down.example.com"}, statusCritical, 2},
+    {[]string{"ok.example.com", "revoked.example.com"}, statusCritical, 2},
+  } {
+    summary, err := CheckSites(&output, new(ndjsonFormatter), tc.sites, check, nil)
+    if err != nil || summary.Status != tc.status || summary.ExitCode != tc.exitCode {
+      t.Errorf("tc%d - expected %s (%d), got %s (%d)", i + 1, tc.status, tc.exitCode, summary.Status, summary.ExitCode)
+    }
+  }
+
+  soon := check("soon.example.com").Output()
+  if soon.ExpiryStatus != statusWarning || soon.SiteStatus != statusWarning || soon.Cert == nil || soon.Cert.DaysRemaining != 19 {
+    t.Errorf("tc6 - expected a warning 19 days out: %+v", soon.Cert)
+  }
+
+  revoked := check("ok.example.com")
+  revoked.revocation = &revocationReport{source: revocationSourceOcsp, status: revocationRevoked}
+  if output := revoked.Output(); output.SiteStatus != statusCritical || output.ExpiryStatus != statusOk {
+    t.Errorf("tc7 - expected a revoked certificate to be critical, got %s (expiry %s)", output.SiteStatus, output.ExpiryStatus)
+  }
+
+  mismatched := check("ok.example.com")
+  mismatched.verification = &verificationReport{failures: []string{verificationHostnameMismatch}}
+  insecure := check("ok.example.com")
+  insecure.insecureFallback = true
+  for _, res := range []*requestResult{mismatched, insecure} {
+    if output := res.Output(); output.SiteStatus != statusCritical || output.ExpiryStatus != statusOk {
+      t.Errorf("tc8 - expected an unverified certificate to be critical, got %s (expiry %s)", output.SiteStatus, output.ExpiryStatus)
+    }
+  }
+}
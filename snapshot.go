@@ -0,0 +1,346 @@
+package main
+
+import(
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+)
+
+/*
+ *  snapshot - every zone and record a provider has at one point in time, saved
+ *  as a json document (see outputSchema) so two of them, or one and the live
+ *  state, can be compared later
+ */
+type snapshotOutput struct {
+  TakenAt time.Time `json:"takenAt"`
+  Provider string `json:"provider"`
+  Zones []*snapshotZoneOutput `json:"zones"`
+}
+type snapshotZoneOutput struct {
+  Zone *zoneOutput `json:"zone"`
+  Records []*recordOutput `json:"records"`
+}
+
+/*
+ *  one difference between two snapshots; changed records list what's different
+ *  about them (values added and removed, the ttl before and after, and any other
+ *  field that changed in details)
+ */
+type recordChangeOutput struct {
+  Change string `json:"change"`
+  ZoneId string `json:"zoneId"`
+  ZoneName string `json:"zoneName"`
+  Name string `json:"name"`
+  Type string `json:"type"`
+  SetIdentifier string `json:"setIdentifier,omitempty"`
+  OldTtl *int64 `json:"oldTtl,omitempty"`
+  NewTtl *int64 `json:"newTtl,omitempty"`
+  AddedValues []string `json:"addedValues"`
+  RemovedValues []string `json:"removedValues"`
+  Details []string `json:"details"`
+}
+
+/*
+ *  Reads every zone and its records from the provider. Records are in the
+ *  exporters' stable order and health check status (which isn't configuration)
+ *  is left out, so unchanged zones give identical snapshots.
+ */
+func TakeSnapshot(provider dnsProvider, takenAt time.Time) (*snapshotOutput, error) {
+  snapshot := &snapshotOutput{Provider: provider.Metadata().name, TakenAt: takenAt, Zones: []*snapshotZoneOutput{}}
+
+  zones, err := provider.GetZones()
+  if err != nil {
+    return nil, err
+  }
+
+  for _, z := range zones {
+    zoneRecords, recordsErr := provider.GetRecordsets(z.id)
+    if recordsErr != nil {
+      return nil, recordsErr
+    }
+
+    snapshotZone := &snapshotZoneOutput{Zone: z.Output(), Records: []*recordOutput{}}
+    for _, rec := range SortedRecords(zoneRecords, z.DomainToString()) {
+      output := rec.Output()
+      output.HealthCheck = nil
+      output.ParsedValues = nil
+      snapshotZone.Records = append(snapshotZone.Records, output)
+    }
+    snapshot.Zones = append(snapshot.Zones, snapshotZone)
+  }
+
+  return snapshot, nil
+}
+
+/*
+ *  snapshots are indented, they're meant to be kept in version control
+ */
+func (snapshot *snapshotOutput) Serialize() string {
+  document, _ := json.MarshalIndent(map[string]interface{}{
+    "schemaVersion": outputSchemaVersion,
+    "snapshot": snapshot,
+  }, "", "  ")
+
+  return string(document) + "\n"
+}
+
+/*
+ *  Reads a snapshot written by -snapshot. Snapshots didn't change from 1.x to
+ *  2.x (only site results did), so either can be read.
+ */
+func LoadSnapshot(path string) (*snapshotOutput, error) {
+  var document struct {
+    SchemaVersion string `json:"schemaVersion"`
+    Snapshot *snapshotOutput `json:"snapshot"`
+  }
+
+  content, err := ioutil.ReadFile(path)
+  if err != nil {
+    return nil, err
+  }
+
+  if err = json.Unmarshal(content, &document); err != nil {
+    return nil, fmt.Errorf("%s: %s", path, err.Error())
+  }
+  knownVersion := strings.HasPrefix(document.SchemaVersion, "1.") || strings.HasPrefix(document.SchemaVersion, "2.")
+  if !knownVersion || document.Snapshot == nil {
+    return nil, fmt.Errorf("%s: not a domania snapshot (schema version \"%s\")", path, document.SchemaVersion)
+  }
+
+  return document.Snapshot, nil
+}
+
+/*
+ *  Compares two snapshots. Zones are matched by id and records by name, type
+ *  and set identifier; the order of a record's values doesn't matter. Changes
+ *  come back ordered by zone, then the way records are exported.
+ */
+func DiffSnapshots(before *snapshotOutput, after *snapshotOutput) []*recordChangeOutput {
+  changes := []*recordChangeOutput{}
+  beforeRecords, beforeZones := snapshotIndex(before)
+  afterRecords, afterZones := snapshotIndex(after)
+
+  for key, beforeRecord := range beforeRecords {
+    afterRecord, found := afterRecords[key]
+    if !found {
+      changes = append(changes, newRecordChange("removed", beforeZones[key], beforeRecord, nil))
+      continue
+    }
+
+    if change := newRecordChange("changed", afterZones[key], beforeRecord, afterRecord); len(change.AddedValues) > 0 || len(change.RemovedValues) > 0 || len(change.Details) > 0 || change.OldTtl != nil {
+      changes = append(changes, change)
+    }
+  }
+  for key, afterRecord := range afterRecords {
+    if _, found := beforeRecords[key]; !found {
+      changes = append(changes, newRecordChange("added", afterZones[key], nil, afterRecord))
+    }
+  }
+
+  sort.Slice(changes, func(i, j int) bool {
+    if changes[i].ZoneName != changes[j].ZoneName {
+      return changes[i].ZoneName < changes[j].ZoneName
+    }
+    if changes[i].ZoneId != changes[j].ZoneId {
+      return changes[i].ZoneId < changes[j].ZoneId
+    }
+
+    iName, jName := canonicalName(changes[i].Name), canonicalName(changes[j].Name)
+    if iName != jName {
+      return iName < jName
+    }
+    if changes[i].Type != changes[j].Type {
+      return changes[i].Type < changes[j].Type
+    }
+
+    return changes[i].SetIdentifier < changes[j].SetIdentifier
+  })
+
+  return changes
+}
+
+/*
+ *  records (and the zone each belongs to) keyed by zone id, name, type and set
+ *  identifier
+ */
+func snapshotIndex(snapshot *snapshotOutput) (map[string]*recordOutput, map[string]*zoneOutput) {
+  records := make(map[string]*recordOutput)
+  zones := make(map[string]*zoneOutput)
+
+  for _, snapshotZone := range snapshot.Zones {
+    for _, rec := range snapshotZone.Records {
+      key := strings.Join([]string{snapshotZone.Zone.Id, strings.ToLower(strings.TrimSuffix(rec.Name, ".")), rec.Type, rec.SetIdentifier}, "\x00")
+      records[key] = rec
+      zones[key] = snapshotZone.Zone
+    }
+  }
+
+  return records, zones
+}
+
+/*
+ *  describes the difference between two versions of a record; either may be nil
+ *  for records that were added or removed
+ */
+func newRecordChange(change string, z *zoneOutput, before *recordOutput, after *recordOutput) *recordChangeOutput {
+  current := after
+  if current == nil {
+    current = before
+  }
+
+  output := &recordChangeOutput{
+    AddedValues: []string{},
+    Change: change,
+    Details: []string{},
+    Name: current.Name,
+    RemovedValues: []string{},
+    SetIdentifier: current.SetIdentifier,
+    Type: current.Type,
+    ZoneId: z.Id,
+    ZoneName: (&zone{subdomain: z.Subdomain, domain: z.Domain, tld: z.Tld}).DomainToString(),
+  }
+
+  switch {
+  case before == nil:
+    output.AddedValues = append(output.AddedValues, after.Values...)
+    output.NewTtl = after.Ttl
+  case after == nil:
+    output.RemovedValues = append(output.RemovedValues, before.Values...)
+    output.OldTtl = before.Ttl
+  default:
+    output.AddedValues = missingValues(after.Values, before.Values)
+    output.RemovedValues = missingValues(before.Values, after.Values)
+    if recordTtl(before) != recordTtl(after) {
+      output.OldTtl, output.NewTtl = before.Ttl, after.Ttl
+    }
+    output.Details = recordDetailChanges(before, after)
+  }
+
+  return output
+}
+
+/*
+ *  values in one list that aren't in the other
+ */
+func missingValues(values []string, from []string) []string {
+  missing := []string{}
+  present := make(map[string]bool)
+
+  for _, value := range from {
+    present[value] = true
+  }
+  for _, value := range values {
+    if !present[value] {
+      missing = append(missing, value)
+    }
+  }
+
+  return missing
+}
+
+/*
+ *  alias records have no ttl of their own
+ */
+func recordTtl(rec *recordOutput) string {
+  if rec.Ttl == nil {
+    return ""
+  }
+
+  return strconv.FormatInt(*rec.Ttl, 10)
+}
+
+/*
+ *  Everything else that can change about a record, as "field: before -> after".
+ */
+func recordDetailChanges(before *recordOutput, after *recordOutput) []string {
+  details := []string{}
+  fields := func(rec *recordOutput) [][2]string {
+    weight, location, evaluateTargetHealth := "", "", ""
+    if rec.Weight != nil {
+      weight = strconv.FormatInt(*rec.Weight, 10)
+    }
+    if rec.GeoLocation != nil {
+      location = strings.Trim(rec.GeoLocation.ContinentCode + "/" + rec.GeoLocation.CountryCode + "/" + rec.GeoLocation.SubdivisionCode, "/")
+    }
+    if rec.EvaluateTargetHealth != nil {
+      evaluateTargetHealth = strconv.FormatBool(*rec.EvaluateTargetHealth)
+    }
+
+    return [][2]string{
+      {"alias", strconv.FormatBool(rec.IsAlias)},
+      {"zoneReference", rec.ZoneReference},
+      {"evaluateTargetHealth", evaluateTargetHealth},
+      {"proxied", strconv.FormatBool(rec.Proxied)},
+      {"weight", weight},
+      {"region", rec.Region},
+      {"geoLocation", location},
+      {"failover", rec.Failover},
+      {"multiValueAnswer", strconv.FormatBool(rec.MultiValueAnswer)},
+      {"healthCheckId", rec.HealthCheckId},
+    }
+  }
+
+  beforeFields, afterFields := fields(before), fields(after)
+  for i := range beforeFields {
+    if beforeFields[i][1] != afterFields[i][1] {
+      details = append(details, fmt.Sprintf("%s: %s -> %s", beforeFields[i][0], emptyAsNone(beforeFields[i][1]), emptyAsNone(afterFields[i][1])))
+    }
+  }
+
+  return details
+}
+func emptyAsNone(value string) string {
+  if len(value) == 0 {
+    return "(none)"
+  }
+
+  return value
+}
+
+func (change *recordChangeOutput) Columns() []string {
+  return []string{"change", "zone", "name", "type", "setIdentifier", "ttl", "value", "details"}
+}
+func (change *recordChangeOutput) Rows() [][]string {
+  var rows [][]string
+  ttl := ""
+
+  switch {
+  case change.OldTtl != nil && change.NewTtl != nil:
+    ttl = strconv.FormatInt(*change.OldTtl, 10) + " -> " + strconv.FormatInt(*change.NewTtl, 10)
+  case change.OldTtl != nil:
+    ttl = strconv.FormatInt(*change.OldTtl, 10)
+  case change.NewTtl != nil:
+    ttl = strconv.FormatInt(*change.NewTtl, 10)
+  }
+
+  //a row per value added or removed, like a unified diff
+  for _, value := range change.RemovedValues {
+    rows = append(rows, []string{change.Change, change.ZoneName, change.Name, change.Type, change.SetIdentifier, ttl, "- " + value, ""})
+  }
+  for _, value := range change.AddedValues {
+    rows = append(rows, []string{change.Change, change.ZoneName, change.Name, change.Type, change.SetIdentifier, ttl, "+ " + value, ""})
+  }
+  if len(rows) == 0 {
+    rows = append(rows, []string{change.Change, change.ZoneName, change.Name, change.Type, change.SetIdentifier, ttl, "", ""})
+  }
+  rows[0][7] = strings.Join(change.Details, "; ")
+
+  return rows
+}
+
+/*
+ *  output items for WriteCollection
+ */
+func RecordChangeItems(changes []*recordChangeOutput) []interface{} {
+  items := make([]interface{}, len(changes))
+
+  for i, change := range changes {
+    items[i] = change
+  }
+
+  return items
+}
@@ -0,0 +1,107 @@
+package main
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+  "time"
+)
+
+/*
+ *  helper that snapshots the export test zone
+ */
+func createSnapshot(t *testing.T, change func(*recordset)) *snapshotOutput {
+  z, zoneRecords := createExportZone()
+  if change != nil {
+    change(zoneRecords)
+  }
+  provider := &bindProvider{recordsets: map[string]*recordset{z.id: zoneRecords}, zones: []*zone{z}}
+
+  snapshot, err := TakeSnapshot(provider, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  return snapshot
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+  dir, _ := ioutil.TempDir("", "domania-snapshot")
+  defer os.RemoveAll(dir)
+  snapshot := createSnapshot(t, func(zoneRecords *recordset) {
+    (*zoneRecords)["A"][1].healthCheck = &healthCheckSummary{id: "hc1", status: "unhealthy"}
+  })
+  path := filepath.Join(dir, "snapshot.json")
+  ioutil.WriteFile(path, []byte(snapshot.Serialize()), 0644)
+  ioutil.WriteFile(filepath.Join(dir, "sites.json"), []byte(serializeDocument("sites", []*siteOutput{})), 0644)
+
+  //test cases
+  //  1: a saved snapshot loads back with every record
+  //  2: health check status isn't part of a snapshot
+  //  3: other documents aren't mistaken for snapshots
+  loaded, err := LoadSnapshot(path)
+  if err != nil || len(loaded.Zones) != 1 || len(loaded.Zones[0].Records) != 9 || loaded.Provider != "bind" || !loaded.TakenAt.Equal(snapshot.TakenAt) {
+    t.Fatalf("tc1 - unexpected snapshot: %+v (error: %v)", loaded, err)
+  }
+
+  if strings.Contains(snapshot.Serialize(), "unhealthy") {
+    t.Error("tc2 - expected health check status to be left out")
+  }
+
+  if _, err = LoadSnapshot(filepath.Join(dir, "sites.json")); err == nil {
+    t.Error("tc3 - expected an error loading a sites document")
+  }
+}
+
+func TestDiffSnapshots(t *testing.T) {
+  before := createSnapshot(t, nil)
+  after := createSnapshot(t, func(zoneRecords *recordset) {
+    weight := int64(20)
+    (*zoneRecords)["A"][1].weight = &weight
+    (*zoneRecords)["A"][2].ttl = 60
+    (*zoneRecords)["A"] = append((*zoneRecords)["A"], &record{name: "api.example.com", ttl: 300, values: []string{"192.0.2.30"}})
+    (*zoneRecords)["MX"][0].values = []string{"10 mail.example.com", "20 backup.example.com"}
+    (*zoneRecords)["NS"][0].values = []string{"ns-2.awsdns-02.net", "ns-1.awsdns-01.org."}
+    delete(*zoneRecords, "CAA")
+  })
+
+  //test cases
+  //  1: identical snapshots have no changes
+  //  2: added and removed records list all their values
+  //  3: changed records show value and ttl changes, and other fields in details
+  //  4: the order of a record's values doesn't count as a change
+  //  5: changes are in export order
+  if changes := DiffSnapshots(before, createSnapshot(t, nil)); len(changes) != 0 {
+    t.Errorf("tc1 - expected no changes, received: %d", len(changes))
+  }
+
+  changes := DiffSnapshots(before, after)
+  if len(changes) != 5 {
+    t.Fatalf("tc2 - expected 5 changes, received: %d", len(changes))
+  }
+  if changes[0].Change != "removed" || changes[0].Type != "CAA" || len(changes[0].RemovedValues) != 1 || changes[3].Change != "added" || changes[3].Name != "api.example.com" || changes[3].AddedValues[0] != "192.0.2.30" || *changes[3].NewTtl != 300 {
+    t.Errorf("tc2 - unexpected changes: %+v, %+v", changes[0], changes[3])
+  }
+
+  mx, wildcard, www := changes[1], changes[2], changes[4]
+  if mx.Change != "changed" || len(mx.AddedValues) != 1 || mx.AddedValues[0] != "20 backup.example.com" || len(mx.RemovedValues) != 0 || mx.OldTtl != nil {
+    t.Errorf("tc3 - unexpected MX change: %+v", mx)
+  }
+  if *wildcard.OldTtl != 300 || *wildcard.NewTtl != 60 || len(wildcard.AddedValues) != 0 {
+    t.Errorf("tc3 - unexpected ttl change: %+v", wildcard)
+  }
+  if len(www.Details) != 1 || www.Details[0] != "weight: 10 -> 20" || www.SetIdentifier != "blue" {
+    t.Errorf("tc3 - unexpected weight change: %+v", www)
+  }
+
+  for _, change := range changes {
+    if change.Type == "NS" {
+      t.Errorf("tc4 - reordered values reported as a change: %+v", change)
+    }
+  }
+
+  if changes[2].Name != "*.example.com" || changes[3].Name != "api.example.com" || changes[4].Name != "www.example.com" {
+    t.Errorf("tc5 - unexpected order: %s, %s, %s", changes[2].Name, changes[3].Name, changes[4].Name)
+  }
+}
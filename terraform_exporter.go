@@ -0,0 +1,291 @@
+package main
+
+import(
+  "fmt"
+  "io"
+  "strconv"
+  "strings"
+)
+
+/*
+ *  terraform exporter - an aws_route53_zone and an aws_route53_record per record
+ *  set. Zones exported from route53 get an import block (terraform 1.5+) for
+ *  each so the existing resources are adopted rather than created; zones from
+ *  other providers don't exist in route53 yet, so they're created. SOA and apex
+ *  NS records belong to the zone resource and are left out.
+ */
+type terraformExporter struct {
+  adoptExisting bool
+}
+func (e *terraformExporter) Extension() string {
+  return "tf"
+}
+func (e *terraformExporter) Export(w io.Writer, z *zone, zoneRecords *recordset) error {
+  var out strings.Builder
+  origin := strings.TrimSuffix(z.DomainToString(), ".")
+  zoneResource := "aws_route53_zone." + TerraformIdentifier(origin)
+  used := make(map[string]bool)
+
+  zoneBlock := &hclBlock{header: "resource \"aws_route53_zone\" \"" + TerraformIdentifier(origin) + "\""}
+  zoneBlock.Attribute("name", HclString(origin))
+  if len(z.comment) > 0 {
+    zoneBlock.Attribute("comment", HclString(z.comment))
+  }
+  fmt.Fprintf(&out, "# %s (zone %s)\n", origin, z.id)
+  if z.private {
+    //vpc associations aren't part of the zone model; without them terraform
+    //would plan a public zone
+    out.WriteString("# private zone: add a vpc block for each associated vpc before applying\n")
+  }
+  out.WriteString(zoneBlock.String())
+  if e.adoptExisting {
+    out.WriteString(TerraformImport(zoneResource, z.id))
+  }
+
+  for _, rec := range SortedRecords(zoneRecords, origin) {
+    isApex := strings.EqualFold(strings.TrimSuffix(rec.name, "."), origin)
+    if rec.rrType == "SOA" || (rec.rrType == "NS" && isApex && !rec.isAlias) {
+      continue
+    }
+
+    //resource names have to be unique in a module
+    identifier := TerraformRecordIdentifier(rec)
+    for suffix := 2; used[identifier]; suffix++ {
+      identifier = TerraformRecordIdentifier(rec) + "_" + strconv.Itoa(suffix)
+    }
+    used[identifier] = true
+
+    recordBlock := &hclBlock{header: "resource \"aws_route53_record\" \"" + identifier + "\""}
+    recordBlock.Attribute("zone_id", zoneResource + ".zone_id")
+    recordBlock.Attribute("name", HclString(strings.TrimSuffix(rec.name, ".")))
+    recordBlock.Attribute("type", HclString(rec.rrType))
+    if rec.isAlias {
+      alias := recordBlock.Block("alias")
+      alias.Attribute("name", HclString(strings.TrimSuffix(strings.Join(rec.values, " "), ".")))
+      alias.Attribute("zone_id", HclString(rec.zoneRef))
+      alias.Attribute("evaluate_target_health", strconv.FormatBool(rec.evaluateTargetHealth))
+    } else {
+      var values []string
+      for _, value := range rec.values {
+        values = append(values, HclString(TerraformRecordValue(rec.rrType, value)))
+      }
+      recordBlock.Attribute("ttl", strconv.FormatInt(rec.ttl, 10))
+      recordBlock.Attribute("records", "[" + strings.Join(values, ", ") + "]")
+    }
+    e.routingPolicy(recordBlock, rec)
+
+    out.WriteString("\n")
+    out.WriteString(recordBlock.String())
+    if e.adoptExisting {
+      out.WriteString(TerraformImport("aws_route53_record." + identifier, TerraformRecordImportId(z.id, rec)))
+    }
+  }
+
+  _, err := io.WriteString(w, out.String())
+  return err
+}
+func (e *terraformExporter) routingPolicy(recordBlock *hclBlock, rec *record) {
+  if len(rec.setIdentifier) > 0 {
+    recordBlock.Attribute("set_identifier", HclString(rec.setIdentifier))
+  }
+  if len(rec.healthCheckId) > 0 {
+    recordBlock.Attribute("health_check_id", HclString(rec.healthCheckId))
+  }
+
+  switch {
+  case rec.weight != nil:
+    recordBlock.Block("weighted_routing_policy").Attribute("weight", strconv.FormatInt(*rec.weight, 10))
+  case len(rec.region) > 0:
+    recordBlock.Block("latency_routing_policy").Attribute("region", HclString(rec.region))
+  case rec.geoLocation != nil:
+    geolocation := recordBlock.Block("geolocation_routing_policy")
+    if len(rec.geoLocation.continentCode) > 0 {
+      geolocation.Attribute("continent", HclString(rec.geoLocation.continentCode))
+    }
+    if len(rec.geoLocation.countryCode) > 0 {
+      geolocation.Attribute("country", HclString(rec.geoLocation.countryCode))
+    }
+    if len(rec.geoLocation.subdivisionCode) > 0 {
+      geolocation.Attribute("subdivision", HclString(rec.geoLocation.subdivisionCode))
+    }
+  case len(rec.failover) > 0:
+    recordBlock.Block("failover_routing_policy").Attribute("type", HclString(rec.failover))
+  case rec.multiValueAnswer:
+    recordBlock.Attribute("multivalue_answer_routing_policy", "true")
+  }
+}
+
+/*
+ *  Record values as the aws provider wants them. It adds the outer quotes of TXT
+ *  and SPF values itself, so one pair comes off (strings that were split at 255
+ *  characters keep their inner "" separators).
+ */
+func TerraformRecordValue(rrType string, value string) string {
+  if rrType == "TXT" || rrType == "SPF" {
+    if len(value) >= 2 && strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
+      return value[1:len(value) - 1]
+    }
+    return value
+  }
+
+  return AbsoluteRecordValue(rrType, value)
+}
+
+/*
+ *  aws provider import id for a record: ZONEID_name_TYPE, plus _SETID for records
+ *  with a routing policy
+ */
+func TerraformRecordImportId(zoneId string, rec *record) string {
+  importId := zoneId + "_" + strings.TrimSuffix(rec.name, ".") + "_" + rec.rrType
+  if len(rec.setIdentifier) > 0 {
+    importId += "_" + rec.setIdentifier
+  }
+
+  return importId
+}
+
+/*
+ *  resource name for a record: its name, type and set identifier
+ *  (www.example.com A blue -> www_example_com_a_blue)
+ */
+func TerraformRecordIdentifier(rec *record) string {
+  identifier := TerraformIdentifier(rec.name) + "_" + strings.ToLower(rec.rrType)
+  if len(rec.setIdentifier) > 0 {
+    identifier += "_" + TerraformIdentifier(rec.setIdentifier)
+  }
+
+  return identifier
+}
+
+/*
+ *  Turns a name into a terraform identifier: letters, digits, underscores and
+ *  dashes, not starting with a digit. Wildcards are spelled out.
+ */
+func TerraformIdentifier(name string) string {
+  var identifier strings.Builder
+  name = strings.ToLower(strings.TrimSuffix(name, "."))
+
+  for i := 0; i < len(name); i++ {
+    c := name[i]
+    switch {
+    case c == '*':
+      identifier.WriteString("wildcard")
+    case (c >= 'a' && c <= 'z') || isDigit(c) || c == '_' || c == '-':
+      identifier.WriteByte(c)
+    default:
+      identifier.WriteByte('_')
+    }
+  }
+
+  if identifier.Len() == 0 || isDigit(identifier.String()[0]) || identifier.String()[0] == '-' {
+    return "_" + identifier.String()
+  }
+
+  return identifier.String()
+}
+
+/*
+ *  import block adopting an existing resource
+ */
+func TerraformImport(resource string, id string) string {
+  importBlock := &hclBlock{header: "import"}
+  importBlock.Attribute("to", resource)
+  importBlock.Attribute("id", HclString(id))
+
+  return "\n" + importBlock.String()
+}
+
+/*
+ *  Quotes a string for HCL, escaping what would otherwise be read as an escape
+ *  or a template sequence.
+ */
+func HclString(text string) string {
+  var quoted strings.Builder
+
+  quoted.WriteString("\"")
+  for i := 0; i < len(text); i++ {
+    c := text[i]
+    switch {
+    case c == '"' || c == '\\':
+      quoted.WriteByte('\\')
+      quoted.WriteByte(c)
+    case c == '\n':
+      quoted.WriteString("\\n")
+    case c == '\r':
+      quoted.WriteString("\\r")
+    case c == '\t':
+      quoted.WriteString("\\t")
+    case (c == '$' || c == '%') && i + 1 < len(text) && text[i + 1] == '{':
+      //${ and %{ start template sequences; doubling the first character escapes them
+      quoted.WriteByte(c)
+      quoted.WriteByte(c)
+    case c < ' ' || c == 0x7f:
+      fmt.Fprintf(&quoted, "\\u%04x", c)
+    default:
+      quoted.WriteByte(c)
+    }
+  }
+  quoted.WriteString("\"")
+
+  return quoted.String()
+}
+
+/*
+ *  hcl block - attributes (already rendered values) and nested blocks, in the
+ *  order they were added; printed the way terraform fmt lays them out
+ */
+type hclBlock struct {
+  header string
+  items []*hclItem
+}
+type hclItem struct {
+  block *hclBlock
+  key string
+  value string
+}
+func (b *hclBlock) Attribute(key string, value string) {
+  b.items = append(b.items, &hclItem{key: key, value: value})
+}
+func (b *hclBlock) Block(header string) *hclBlock {
+  nested := &hclBlock{header: header}
+  b.items = append(b.items, &hclItem{block: nested})
+
+  return nested
+}
+func (b *hclBlock) String() string {
+  var out strings.Builder
+
+  b.write(&out, "")
+  return out.String()
+}
+func (b *hclBlock) write(out *strings.Builder, indent string) {
+  fmt.Fprintf(out, "%s%s {\n", indent, b.header)
+
+  for i := 0; i < len(b.items); i++ {
+    if b.items[i].block != nil {
+      //blocks are set apart from the attributes around them
+      if i > 0 {
+        out.WriteString("\n")
+      }
+      b.items[i].block.write(out, indent + "  ")
+      if i + 1 < len(b.items) {
+        out.WriteString("\n")
+      }
+      continue
+    }
+
+    //consecutive attributes line up on their equals signs
+    end, width := i, 0
+    for ; end < len(b.items) && b.items[end].block == nil; end++ {
+      if len(b.items[end].key) > width {
+        width = len(b.items[end].key)
+      }
+    }
+    for ; i < end; i++ {
+      fmt.Fprintf(out, "%s  %-*s = %s\n", indent, width, b.items[i].key, b.items[i].value)
+    }
+    i--
+  }
+
+  fmt.Fprintf(out, "%s}\n", indent)
+}
@@ -0,0 +1,61 @@
+package main
+import (
+  "strings"
+  "testing"
+)
+
+func TestTerraformExporterExport(t *testing.T) {
+  var output strings.Builder
+  z, zoneRecords := createExportZone()
+  z.comment = "managed by ${team}"
+  (*zoneRecords)["A"] = append((*zoneRecords)["A"], &record{name: "api.example.com", ttl: 60, setIdentifier: "eu", geoLocation: &geoLocation{continentCode: "EU"}, values: []string{"192.0.2.20"}})
+
+  //test cases
+  //  1: the zone resource and its import block; template sequences are escaped
+  //  2: SOA and apex NS records are left to the zone
+  //  3: alias records get an alias block instead of ttl and records
+  //  4: routing policies get their block, set identifiers end up in the import id
+  //  5: TXT values lose their outer quotes, the rest is escaped for HCL
+  //  6: zones from other providers get no import blocks
+  err := (&terraformExporter{adoptExisting: true}).Export(&output, z, zoneRecords)
+  tf := output.String()
+  if err != nil || !strings.Contains(tf, "resource \"aws_route53_zone\" \"example_com\" {\n  name    = \"example.com\"\n  comment = \"managed by $${team}\"\n}\n") || !strings.Contains(tf, "import {\n  to = aws_route53_zone.example_com\n  id = \"Z1EXAMPLE\"\n}\n") {
+    t.Errorf("tc1 - unexpected zone: %s (error: %v)", tf, err)
+  }
+
+  if strings.Contains(tf, "\"SOA\"") || strings.Contains(tf, "awsdns-02") || !strings.Contains(tf, "resource \"aws_route53_record\" \"dev_example_com_ns\"") {
+    t.Errorf("tc2 - expected SOA and apex NS to be skipped: %s", tf)
+  }
+
+  if !strings.Contains(tf, "  alias {\n    name                   = \"dualstack.lb.amazonaws.com\"\n    zone_id                = \"Z35SXDOTRQ7X7K\"\n    evaluate_target_health = true\n  }\n") || strings.Contains(tf, "ttl     = 0") {
+    t.Errorf("tc3 - unexpected alias record: %s", tf)
+  }
+
+  if !strings.Contains(tf, "  weighted_routing_policy {\n    weight = 10\n  }\n") || !strings.Contains(tf, "id = \"Z1EXAMPLE_www.example.com_A_blue\"") || !strings.Contains(tf, "    continent = \"EU\"\n") || !strings.Contains(tf, "resource \"aws_route53_record\" \"wildcard_example_com_a\"") {
+    t.Errorf("tc4 - unexpected routing policies: %s", tf)
+  }
+
+  if !strings.Contains(tf, "records = [\"v=spf1 a mx; ~all\\\" \\\"say \\\\\\\"hi\\\\\\\", \\\\\\\\ \\\\009tab\", ") {
+    t.Errorf("tc5 - unexpected TXT records: %s", tf)
+  }
+
+  output.Reset()
+  exporter, _ := NewZoneExporter("terraform", "cloudflare")
+  exporter.Export(&output, z, zoneRecords)
+  if tf = output.String(); strings.Contains(tf, "import {") || !strings.Contains(tf, "resource \"aws_route53_record\" \"dev_example_com_ns\"") {
+    t.Errorf("tc6 - expected records without import blocks: %s", tf)
+  }
+}
+
+func TestTerraformIdentifier(t *testing.T) {
+  //test cases
+  //  1: dots and other punctuation become underscores, wildcards are spelled out
+  //  2: identifiers can't start with a digit
+  if tc1 := TerraformIdentifier("*._DMARC.example.com."); tc1 != "wildcard__dmarc_example_com" {
+    t.Errorf("tc1 - unexpected identifier: %s", tc1)
+  }
+
+  if tc2 := TerraformIdentifier("1.example.com"); tc2 != "_1_example_com" {
+    t.Errorf("tc2 - unexpected identifier: %s", tc2)
+  }
+}
@@ -0,0 +1,186 @@
+package main
+
+import(
+  "crypto/tls"
+  "net"
+  "strings"
+  "time"
+)
+
+//how long each probe connection gets to finish its handshake
+var tlsProbeTimeout time.Duration = 5 * time.Second
+
+//protocol versions probed, oldest first, and those that are deprecated (RFC 8996)
+var tlsProbeVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+var deprecatedTlsVersions = map[uint16]bool{tls.VersionSSL30: true, tls.VersionTLS10: true, tls.VersionTLS11: true}
+
+/*
+ *  deep tls probe - every protocol version and cipher suite a server accepts,
+ *  found by connecting once per version and once per (version, suite) with
+ *  nothing else offered. TLS 1.3 suites can't be offered one at a time, so for
+ *  1.3 only the suite the server picks is known.
+ */
+type tlsProbeReport struct {
+  findings []string
+  suites []*tlsSuiteSupport
+  versions []*tlsVersionSupport
+}
+type tlsVersionSupport struct {
+  deprecated bool
+  supported bool
+  version uint16
+}
+type tlsSuiteSupport struct {
+  suite uint16
+  version uint16
+  weakness string
+}
+
+/*
+ *  Probes address (host:port) presenting serverName for SNI. Certificates aren't
+ *  verified; this is only about what the server will negotiate.
+ */
+func ProbeTLS(address string, serverName string) *tlsProbeReport {
+  report := &tlsProbeReport{findings: []string{}}
+  suitesByVersion := make(map[uint16][]uint16)
+
+  for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+    for _, version := range suite.SupportedVersions {
+      suitesByVersion[version] = append(suitesByVersion[version], suite.ID)
+    }
+  }
+
+  for _, version := range tlsProbeVersions {
+    //offering every suite, not just the default ones, so a version only served
+    //with weak suites still counts as supported (1.3 suites aren't configurable)
+    var offered []uint16
+    if version != tls.VersionTLS13 {
+      offered = suitesByVersion[version]
+    }
+    state, err := probeHandshake(address, serverName, version, offered)
+    support := &tlsVersionSupport{deprecated: deprecatedTlsVersions[version], supported: err == nil, version: version}
+    report.versions = append(report.versions, support)
+    if !support.supported {
+      continue
+    }
+
+    if support.deprecated {
+      report.findings = append(report.findings, "deprecated protocol accepted: " + tlsVersionsByCode[version])
+    }
+
+    if version == tls.VersionTLS13 {
+      report.addSuite(version, state.CipherSuite)
+      continue
+    }
+    for _, suite := range offered {
+      if _, suiteErr := probeHandshake(address, serverName, version, []uint16{suite}); suiteErr == nil {
+        report.addSuite(version, suite)
+      }
+    }
+  }
+
+  return report
+}
+func (report *tlsProbeReport) addSuite(version uint16, suite uint16) {
+  support := &tlsSuiteSupport{suite: suite, version: version, weakness: CipherSuiteWeakness(suite)}
+  report.suites = append(report.suites, support)
+
+  if len(support.weakness) > 0 {
+    report.findings = append(report.findings, "weak cipher suite accepted with " + tlsVersionsByCode[version] + ": " + CipherSuiteName(suite) + " (" + support.weakness + ")")
+  }
+}
+
+/*
+ *  one handshake, pinned to a single version (and suites, if given)
+ */
+func probeHandshake(address string, serverName string, version uint16, suites []uint16) (tls.ConnectionState, error) {
+  dialer := &net.Dialer{Timeout: tlsProbeTimeout}
+  conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{
+    CipherSuites: suites,
+    InsecureSkipVerify: true,
+    MaxVersion: version,
+    MinVersion: version,
+    ServerName: serverName,
+  })
+  if err != nil {
+    return tls.ConnectionState{}, err
+  }
+  defer conn.Close()
+
+  return conn.ConnectionState(), nil
+}
+
+/*
+ *  Why a suite is weak, or "" if it isn't: RC4 and 3DES ciphers, and the rest
+ *  of what crypto/tls considers insecure (no forward secrecy, CBC-SHA256).
+ */
+func CipherSuiteWeakness(suite uint16) string {
+  name := CipherSuiteName(suite)
+
+  switch {
+  case strings.Contains(name, "RC4"):
+    return "RC4 is broken"
+  case strings.Contains(name, "3DES"):
+    return "3DES has 64-bit blocks (Sweet32)"
+  case strings.HasPrefix(name, "TLS_RSA_"):
+    return "RSA key exchange has no forward secrecy"
+  }
+
+  for _, insecure := range tls.InsecureCipherSuites() {
+    if insecure.ID == suite {
+      return "crypto/tls considers it insecure"
+    }
+  }
+
+  return ""
+}
+
+/*
+ *  suite names come from cipherSuitesByCode, falling back to crypto/tls's
+ */
+func CipherSuiteName(suite uint16) string {
+  if name, known := cipherSuitesByCode[suite]; known {
+    return name
+  }
+
+  return tls.CipherSuiteName(suite)
+}
+
+/*
+ *  Wraps a site check so https sites also get a deep tls probe, of the host the
+ *  check's last request went to (after redirects).
+ */
+func ProbingCheck(check func(string) *requestResult) func(string) *requestResult {
+  return func(site string) *requestResult {
+    result := check(site)
+    if !result.responseEncrypted || result.rawResponse.Request == nil {
+      return result
+    }
+
+    siteUrl := result.rawResponse.Request.URL
+    port := siteUrl.Port()
+    if len(port) == 0 {
+      port = "443"
+    }
+    result.tlsProbe = ProbeTLS(net.JoinHostPort(siteUrl.Hostname(), port), siteUrl.Hostname())
+
+    return result
+  }
+}
+
+func (report *tlsProbeReport) Output() *tlsProbeOutput {
+  output := &tlsProbeOutput{
+    CipherSuites: []*tlsSuiteOutput{},
+    Findings: nonNilStrings(report.findings),
+    Versions: []*tlsVersionOutput{},
+  }
+
+  for _, version := range report.versions {
+    output.Versions = append(output.Versions, &tlsVersionOutput{Deprecated: version.deprecated, Supported: version.supported, Version: tlsVersionsByCode[version.version]})
+  }
+  for _, suite := range report.suites {
+    output.CipherSuites = append(output.CipherSuites, &tlsSuiteOutput{Suite: CipherSuiteName(suite.suite), Version: tlsVersionsByCode[suite.version], Weak: len(suite.weakness) > 0, Weakness: suite.weakness})
+  }
+
+  return output
+}
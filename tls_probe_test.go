@@ -0,0 +1,162 @@
+package main
+import (
+  "crypto/tls"
+  "crypto/x509"
+  "net"
+  "strings"
+  "testing"
+  "time"
+)
+
+/*
+ *  helper serving tls with config until the test ends; returns its address
+ */
+func startTestTLSListener(t *testing.T, config *tls.Config) string {
+  listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+  if err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(func() { listener.Close() })
+
+  go func() {
+    for {
+      conn, acceptErr := listener.Accept()
+      if acceptErr != nil {
+        return
+      }
+      go func() {
+        defer conn.Close()
+        conn.SetDeadline(time.Now().Add(5 * time.Second))
+        conn.(*tls.Conn).Handshake()
+      }()
+    }
+  }()
+
+  return listener.Addr().String()
+}
+
+func TestProbeTLS(t *testing.T) {
+  var report *tlsProbeReport
+  certificate := createTestCertificateWith(t, "www.example.com", nil, false, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), testCertificateOptions{
+    dnsNames: []string{"www.example.com"},
+    keyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+    rsaKey: true,
+  }).TLSCertificate()
+
+  //test cases
+  // 1. a server allowing TLS 1.0 - 1.2 with a modern and a 3DES suite
+  // 2. a TLS 1.3 only server
+  // 3. nothing listening
+
+  //tc1
+  address := startTestTLSListener(t, &tls.Config{
+    Certificates: []tls.Certificate{certificate},
+    CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA},
+    MaxVersion: tls.VersionTLS12,
+    MinVersion: tls.VersionTLS10,
+  })
+  report = ProbeTLS(address, "www.example.com")
+  if len(report.versions) != 4 {
+    t.Fatalf("tc1 - expected 4 versions probed, got %d", len(report.versions))
+  }
+  for i, expected := range []bool{true, true, true, false} {
+    if report.versions[i].supported != expected {
+      t.Errorf("tc1 - expected %s supported to be %t", tlsVersionsByCode[report.versions[i].version], expected)
+    }
+  }
+  if !report.versions[0].deprecated || !report.versions[1].deprecated || report.versions[2].deprecated {
+    t.Errorf("tc1 - expected only TLS 1.0 and 1.1 to be deprecated")
+  }
+
+  accepted := make(map[string]bool)
+  for _, suite := range report.suites {
+    accepted[tlsVersionsByCode[suite.version] + " " + CipherSuiteName(suite.suite)] = len(suite.weakness) > 0
+  }
+  if len(accepted) != 4 {
+    t.Errorf("tc1 - expected 4 accepted (version, suite) pairs, got %v", accepted)
+  }
+  for key, weak := range map[string]bool{
+    "VersionTLS10 TLS_RSA_WITH_3DES_EDE_CBC_SHA": true,
+    "VersionTLS11 TLS_RSA_WITH_3DES_EDE_CBC_SHA": true,
+    "VersionTLS12 TLS_RSA_WITH_3DES_EDE_CBC_SHA": true,
+    "VersionTLS12 TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256": false,
+  } {
+    if isWeak, found := accepted[key]; !found || isWeak != weak {
+      t.Errorf("tc1 - expected %s to be accepted (weak: %t)", key, weak)
+    }
+  }
+  //two deprecated versions and the 3DES suite with three of them
+  if len(report.findings) != 5 {
+    t.Errorf("tc1 - expected 5 findings, got %v", report.findings)
+  }
+  if !strings.Contains(strings.Join(report.findings, "\n"), "deprecated protocol accepted: VersionTLS10") {
+    t.Errorf("tc1 - expected TLS 1.0 to be called out, got %v", report.findings)
+  }
+
+  //tc2
+  address = startTestTLSListener(t, &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS13})
+  report = ProbeTLS(address, "www.example.com")
+  if report.versions[2].supported || !report.versions[3].supported {
+    t.Errorf("tc2 - expected only TLS 1.3 to be supported")
+  }
+  if len(report.suites) != 1 || report.suites[0].version != tls.VersionTLS13 {
+    t.Errorf("tc2 - expected the negotiated TLS 1.3 suite, got %d suites", len(report.suites))
+  }
+  if len(report.findings) != 0 {
+    t.Errorf("tc2 - expected no findings, got %v", report.findings)
+  }
+
+  //tc3
+  listener, _ := net.Listen("tcp", "127.0.0.1:0")
+  address = listener.Addr().String()
+  listener.Close()
+  report = ProbeTLS(address, "")
+  for _, version := range report.versions {
+    if version.supported {
+      t.Errorf("tc3 - expected %s to be unsupported", tlsVersionsByCode[version.version])
+    }
+  }
+  if len(report.suites) != 0 || len(report.findings) != 0 {
+    t.Errorf("tc3 - expected no suites or findings")
+  }
+}
+
+func TestCipherSuiteWeakness(t *testing.T) {
+  //test cases
+  // 1. RC4
+  // 2. 3DES
+  // 3. RSA key exchange
+  // 4. CBC-SHA256 with forward secrecy
+  // 5. a modern suite
+  // 6. a suite missing from cipherSuitesByCode is still named
+
+  //tc1
+  if weakness := CipherSuiteWeakness(tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA); !strings.Contains(weakness, "RC4") {
+    t.Errorf("tc1 - expected RC4 to be weak, got \"%s\"", weakness)
+  }
+
+  //tc2
+  if weakness := CipherSuiteWeakness(tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA); !strings.Contains(weakness, "3DES") {
+    t.Errorf("tc2 - expected 3DES to be weak, got \"%s\"", weakness)
+  }
+
+  //tc3
+  if weakness := CipherSuiteWeakness(tls.TLS_RSA_WITH_AES_128_GCM_SHA256); !strings.Contains(weakness, "forward secrecy") {
+    t.Errorf("tc3 - expected RSA key exchange to be weak, got \"%s\"", weakness)
+  }
+
+  //tc4
+  if weakness := CipherSuiteWeakness(tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256); len(weakness) == 0 {
+    t.Errorf("tc4 - expected CBC-SHA256 to be weak")
+  }
+
+  //tc5
+  if weakness := CipherSuiteWeakness(tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256); len(weakness) != 0 {
+    t.Errorf("tc5 - expected no weakness, got \"%s\"", weakness)
+  }
+
+  //tc6
+  if name := CipherSuiteName(0xfafa); name != tls.CipherSuiteName(0xfafa) {
+    t.Errorf("tc6 - expected crypto/tls's name, got \"%s\"", name)
+  }
+}
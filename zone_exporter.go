@@ -0,0 +1,183 @@
+package main
+
+import(
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+  "sort"
+  "strings"
+)
+
+/*
+ *  zone exporter - writes a zone and its records in another tool's format; works
+ *  from the provider-neutral zone/recordset model, so zones from any provider
+ *  can be exported
+ */
+type zoneExporter interface {
+  Export(w io.Writer, z *zone, zoneRecords *recordset) error
+  //file name extension used when exporting several zones into a directory
+  Extension() string
+}
+
+/*
+ *  Picks the exporter for an -export argument; providerName is the provider the
+ *  zones come from.
+ */
+func NewZoneExporter(format string, providerName string) (zoneExporter, error) {
+  switch strings.ToLower(format) {
+  case "bind":
+    return new(bindExporter), nil
+  case "terraform":
+    return &terraformExporter{adoptExisting: providerName == "route53"}, nil
+  case "octodns":
+    return new(octodnsExporter), nil
+  case "dnscontrol":
+    return new(dnscontrolExporter), nil
+  }
+
+  return nil, fmt.Errorf("unknown export format \"%s\" (bind, terraform, octodns, dnscontrol)", format)
+}
+
+/*
+ *  Every record in the recordset in a stable order (SOA, then apex NS, then by
+ *  name with parents ahead of their children, then by type) so repeat exports
+ *  diff cleanly.
+ */
+func SortedRecords(zoneRecords *recordset, origin string) []*record {
+  var records []*record
+  origin = strings.ToLower(strings.TrimSuffix(origin, "."))
+
+  for rrType, typeRecords := range *zoneRecords {
+    for _, rec := range typeRecords {
+      //records built without a type (tests, mostly) take the map's
+      if len(rec.rrType) == 0 {
+        typed := *rec
+        typed.rrType = rrType
+        rec = &typed
+      }
+      records = append(records, rec)
+    }
+  }
+
+  rank := func(rec *record) int {
+    isApex := strings.ToLower(rec.name) == origin
+    switch {
+    case rec.rrType == "SOA":
+      return 0
+    case rec.rrType == "NS" && isApex:
+      return 1
+    }
+    return 2
+  }
+
+  sort.SliceStable(records, func(i, j int) bool {
+    if rank(records[i]) != rank(records[j]) {
+      return rank(records[i]) < rank(records[j])
+    }
+
+    iName, jName := canonicalName(records[i].name), canonicalName(records[j].name)
+    if iName != jName {
+      return iName < jName
+    }
+    if records[i].rrType != records[j].rrType {
+      return records[i].rrType < records[j].rrType
+    }
+
+    return records[i].setIdentifier < records[j].setIdentifier
+  })
+
+  return records
+}
+
+/*
+ *  a name with its labels reversed (www.example.com -> com example www) so
+ *  sorting keeps a name's children together, right after it
+ */
+func canonicalName(name string) string {
+  labels := strings.Split(strings.ToLower(strings.TrimSuffix(name, ".")), ".")
+
+  for i, j := 0, len(labels) - 1; i < j; i, j = i + 1, j - 1 {
+    labels[i], labels[j] = labels[j], labels[i]
+  }
+
+  return strings.Join(labels, " ")
+}
+
+/*
+ *  Domain names in record data are always absolute; providers that leave off
+ *  the trailing dot (cloudflare) get one added so the value can't be read as
+ *  relative to the zone.
+ */
+func AbsoluteRecordValue(rrType string, value string) string {
+  fields := strings.Fields(value)
+  nameField := -1
+
+  switch rrType {
+  case "CNAME", "NS", "PTR", "DNAME":
+    nameField = 0
+  case "MX":
+    nameField = 1
+  case "SRV":
+    nameField = 3
+  }
+
+  if nameField < 0 || nameField >= len(fields) || strings.HasSuffix(fields[nameField], ".") {
+    return value
+  }
+
+  fields[nameField] += "."
+  return strings.Join(fields, " ")
+}
+
+/*
+ *  Exports every zone the provider has into dir, one file per zone named after
+ *  it (<zone>.<extension>). Zones sharing a name (eg. public and private views,
+ *  or several accounts) get their id added to the file name. Returns the files
+ *  written.
+ */
+func ExportZoneFiles(provider dnsProvider, exporter zoneExporter, dir string) ([]string, error) {
+  var written []string
+  used := make(map[string]bool)
+
+  zones, err := provider.GetZones()
+  if err != nil {
+    return nil, err
+  }
+
+  if err = os.MkdirAll(dir, 0755); err != nil {
+    return nil, err
+  }
+
+  for _, z := range zones {
+    zoneRecords, recordsErr := provider.GetRecordsets(z.id)
+    if recordsErr != nil {
+      return written, recordsErr
+    }
+
+    fileName := strings.ToLower(z.DomainToString()) + "." + exporter.Extension()
+    if used[fileName] {
+      fileName = strings.ToLower(z.DomainToString()) + "_" + strings.Replace(z.id, "/", "_", -1) + "." + exporter.Extension()
+    }
+    used[fileName] = true
+
+    path := filepath.Join(dir, fileName)
+    file, createErr := os.Create(path)
+    if createErr != nil {
+      return written, createErr
+    }
+
+    exportErr := exporter.Export(file, z, zoneRecords)
+    closeErr := file.Close()
+    if exportErr == nil {
+      exportErr = closeErr
+    }
+    if exportErr != nil {
+      return written, fmt.Errorf("%s: %s", path, exportErr.Error())
+    }
+
+    written = append(written, path)
+  }
+
+  return written, nil
+}
@@ -0,0 +1,80 @@
+package main
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "testing"
+)
+
+func TestSortedRecords(t *testing.T) {
+  z, zoneRecords := createExportZone()
+  records := SortedRecords(zoneRecords, z.DomainToString())
+  var order []string
+  for _, rec := range records {
+    order = append(order, rec.rrType + " " + rec.name)
+  }
+
+  //test cases
+  //  1: SOA, then apex NS, then names with parents ahead of children, then by type
+  //  2: records without a type take the recordset's
+  expected := []string{"SOA example.com", "NS example.com", "A example.com", "CAA example.com", "MX example.com", "TXT example.com", "A *.example.com", "NS dev.example.com", "A www.example.com"}
+  if len(order) != len(expected) {
+    t.Fatalf("tc1 - expected %d records, received: %v", len(expected), order)
+  }
+  for i := range expected {
+    if order[i] != expected[i] {
+      t.Errorf("tc1 - expected %v, received: %v", expected, order)
+      break
+    }
+  }
+
+  if (*zoneRecords)["SOA"][0].rrType != "" {
+    t.Error("tc2 - the recordset's own records should be left as they were")
+  }
+}
+
+func TestAbsoluteRecordValue(t *testing.T) {
+  //test cases
+  //  1: CNAME, MX and SRV targets get a trailing dot
+  //  2: names that are already absolute, and other types, are unchanged
+  if tc1 := AbsoluteRecordValue("CNAME", "www.example.com"); tc1 != "www.example.com." {
+    t.Errorf("tc1 - unexpected CNAME: %s", tc1)
+  }
+  if tc1 := AbsoluteRecordValue("MX", "10 mail.example.com"); tc1 != "10 mail.example.com." {
+    t.Errorf("tc1 - unexpected MX: %s", tc1)
+  }
+  if tc1 := AbsoluteRecordValue("SRV", "1 2 443 sip.example.com"); tc1 != "1 2 443 sip.example.com." {
+    t.Errorf("tc1 - unexpected SRV: %s", tc1)
+  }
+
+  if tc2 := AbsoluteRecordValue("NS", "ns1.example.net."); tc2 != "ns1.example.net." {
+    t.Errorf("tc2 - unexpected NS: %s", tc2)
+  }
+  if tc2 := AbsoluteRecordValue("A", "192.0.2.1"); tc2 != "192.0.2.1" {
+    t.Errorf("tc2 - unexpected A: %s", tc2)
+  }
+}
+
+func TestExportZoneFiles(t *testing.T) {
+  dir, _ := ioutil.TempDir("", "domania-export")
+  defer os.RemoveAll(dir)
+  z, zoneRecords := createExportZone()
+  second := &zone{id: "Z2EXAMPLE"}
+  second.SetName("example.com")
+  provider := &bindProvider{
+    recordsets: map[string]*recordset{z.id: zoneRecords, second.id: &recordset{}},
+    zones: []*zone{z, second},
+  }
+
+  //test cases
+  //  1: a file per zone, named after the zone
+  //  2: a second zone with the same name gets its id in the file name
+  files, err := ExportZoneFiles(provider, new(bindExporter), filepath.Join(dir, "zones"))
+  if err != nil || len(files) != 2 || filepath.Base(files[0]) != "example.com.zone" {
+    t.Fatalf("tc1 - unexpected files: %v (error: %v)", files, err)
+  }
+
+  if filepath.Base(files[1]) != "example.com_Z2EXAMPLE.zone" {
+    t.Errorf("tc2 - unexpected file name: %s", files[1])
+  }
+}
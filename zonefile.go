@@ -0,0 +1,442 @@
+package main
+
+import(
+  "errors"
+  "fmt"
+  "io/ioutil"
+  "path/filepath"
+  "strings"
+  "unicode"
+)
+
+/*
+ *  a single resource record read from a master (zone) file; names are fully
+ *  qualified (trailing dot) and rdata is kept as the original tokens
+ */
+type zoneFileRecord struct {
+  class string
+  name string
+  rdata []string
+  rrType string
+  ttl int64
+}
+
+/*
+ *  contents of a master file, including anything it pulled in with $INCLUDE
+ */
+type zoneFile struct {
+  origin string
+  records []*zoneFileRecord
+}
+
+/*
+ *  one logical entry (directive or record) from a master file; parentheses let
+ *  an entry span several physical lines
+ */
+type zoneFileEntry struct {
+  line int
+  ownerOmitted bool
+  tokens []string
+}
+
+//record classes we'll recognize in place of (or alongside) a ttl
+var zoneFileClasses = map[string]bool{"IN": true, "CH": true, "CS": true, "HS": true}
+//rdata positions holding domain names, which may be relative to the origin
+var zoneFileNameFields = map[string][]int{
+  "AFSDB": []int{1},
+  "CNAME": []int{0},
+  "DNAME": []int{0},
+  "MX": []int{1},
+  "NS": []int{0},
+  "PTR": []int{0},
+  "RP": []int{0, 1},
+  "SOA": []int{0, 1},
+  "SRV": []int{3},
+}
+
+/*
+ *  Reads an RFC 1035 master file. The origin may be empty if the file sets its
+ *  own with $ORIGIN; it's required before any relative name is used.
+ */
+func ParseZoneFile(path string, origin string) (*zoneFile, error) {
+  zf := &zoneFile{origin: FullyQualify(origin, ".")}
+  parser := &zoneFileParser{zf: zf, origin: zf.origin}
+
+  if err := parser.parseFile(path, 0); err != nil {
+    return nil, err
+  }
+
+  //a file that sets its own origin gets to name the zone
+  if len(zf.origin) == 0 || zf.origin == "." {
+    zf.origin = parser.firstOrigin
+  }
+  if len(zf.origin) == 0 {
+    return nil, fmt.Errorf("%s: unable to determine the zone's origin", path)
+  }
+
+  return zf, nil
+}
+
+/*
+ *  Appends the origin to relative names ("@" is the origin itself). Names that
+ *  already end in a dot are left alone.
+ */
+func FullyQualify(name string, origin string) string {
+  if len(name) == 0 {
+    return ""
+  }
+
+  if name == "@" {
+    return origin
+  }
+
+  if strings.HasSuffix(name, ".") && !strings.HasSuffix(name, "\\.") {
+    return name
+  }
+
+  if origin == "." {
+    return name + "."
+  }
+
+  return name + "." + origin
+}
+
+/*
+ *  Master file ttls are seconds or BIND style units (eg. 1h30m, 2d, 1w).
+ */
+func ParseZoneFileTTL(token string) (int64, error) {
+  var total, current int64
+  var sawDigit bool
+  units := map[rune]int64{'s': 1, 'm': 60, 'h': 3600, 'd': 86400, 'w': 604800}
+
+  if len(token) == 0 || !unicode.IsDigit(rune(token[0])) {
+    return 0, fmt.Errorf("invalid ttl \"%s\"", token)
+  }
+
+  for _, c := range strings.ToLower(token) {
+    if unicode.IsDigit(c) {
+      current = current * 10 + int64(c - '0')
+      sawDigit = true
+    } else if multiplier, isUnit := units[c]; isUnit && sawDigit {
+      total += current * multiplier
+      current = 0
+      sawDigit = false
+    } else {
+      return 0, fmt.Errorf("invalid ttl \"%s\"", token)
+    }
+  }
+
+  return total + current, nil
+}
+
+/*
+ *  parser state; origin, ttl and previous owner change as the file is read
+ */
+type zoneFileParser struct {
+  defaultTTL int64
+  firstOrigin string
+  hasDefaultTTL bool
+  lastOwner string
+  lastTTL int64
+  origin string
+  zf *zoneFile
+}
+func (p *zoneFileParser) parseFile(path string, depth int) error {
+  if depth > 10 {
+    return errors.New(path + ": $INCLUDE nested too deeply")
+  }
+
+  content, err := ioutil.ReadFile(path)
+  if err != nil {
+    return err
+  }
+
+  entries, err := TokenizeZoneFile(string(content))
+  if err != nil {
+    return fmt.Errorf("%s: %s", path, err.Error())
+  }
+
+  for _, entry := range entries {
+    if err = p.parseEntry(path, entry, depth); err != nil {
+      return fmt.Errorf("%s:%d: %s", path, entry.line, err.Error())
+    }
+  }
+
+  return nil
+}
+func (p *zoneFileParser) parseEntry(path string, entry *zoneFileEntry, depth int) error {
+  tokens := entry.tokens
+
+  //directives
+  if !entry.ownerOmitted && strings.HasPrefix(tokens[0], "$") {
+    switch strings.ToUpper(tokens[0]) {
+    case "$ORIGIN":
+      if len(tokens) < 2 {
+        return errors.New("$ORIGIN requires a domain name")
+      }
+
+      p.origin = FullyQualify(tokens[1], p.origin)
+      if !strings.HasSuffix(p.origin, ".") {
+        return errors.New("$ORIGIN must be fully qualified")
+      }
+
+      if len(p.firstOrigin) == 0 {
+        p.firstOrigin = p.origin
+      }
+    case "$TTL":
+      if len(tokens) < 2 {
+        return errors.New("$TTL requires a value")
+      }
+
+      ttl, err := ParseZoneFileTTL(tokens[1])
+      if err != nil {
+        return err
+      }
+
+      p.defaultTTL = ttl
+      p.hasDefaultTTL = true
+    case "$INCLUDE":
+      if len(tokens) < 2 {
+        return errors.New("$INCLUDE requires a file name")
+      }
+
+      includePath := tokens[1]
+      if !filepath.IsAbs(includePath) {
+        includePath = filepath.Join(filepath.Dir(path), includePath)
+      }
+
+      //the origin (and owner) revert once the included file has been read
+      savedOrigin, savedOwner := p.origin, p.lastOwner
+      if len(tokens) > 2 {
+        p.origin = FullyQualify(tokens[2], p.origin)
+      }
+
+      err := p.parseFile(includePath, depth + 1)
+      p.origin, p.lastOwner = savedOrigin, savedOwner
+      return err
+    default:
+      return fmt.Errorf("unsupported directive %s", tokens[0])
+    }
+
+    return nil
+  }
+
+  return p.parseRecord(tokens, entry.ownerOmitted)
+}
+func (p *zoneFileParser) parseRecord(tokens []string, ownerOmitted bool) error {
+  rec := new(zoneFileRecord)
+  hasTTL := false
+
+  //owner
+  if ownerOmitted {
+    if len(p.lastOwner) == 0 {
+      return errors.New("record has no owner name")
+    }
+    rec.name = p.lastOwner
+  } else {
+    if p.requiresOrigin(tokens[0]) {
+      return fmt.Errorf("relative name \"%s\" used before $ORIGIN", tokens[0])
+    }
+    rec.name = FullyQualify(tokens[0], p.origin)
+    tokens = tokens[1:]
+  }
+
+  //ttl and class may appear in either order ahead of the type
+  for len(tokens) > 0 {
+    if zoneFileClasses[strings.ToUpper(tokens[0])] {
+      rec.class = strings.ToUpper(tokens[0])
+    } else if unicode.IsDigit(rune(tokens[0][0])) {
+      ttl, err := ParseZoneFileTTL(tokens[0])
+      if err != nil {
+        return err
+      }
+      rec.ttl = ttl
+      hasTTL = true
+    } else {
+      break
+    }
+
+    tokens = tokens[1:]
+  }
+
+  if len(tokens) == 0 {
+    return errors.New("record is missing a type")
+  }
+
+  rec.rrType = strings.ToUpper(tokens[0])
+  rec.rdata = tokens[1:]
+  if len(rec.class) == 0 {
+    rec.class = "IN"
+  }
+
+  //domain names in rdata can be relative too
+  for _, field := range zoneFileNameFields[rec.rrType] {
+    if field < len(rec.rdata) {
+      if p.requiresOrigin(rec.rdata[field]) {
+        return fmt.Errorf("relative name \"%s\" used before $ORIGIN", rec.rdata[field])
+      }
+      rec.rdata[field] = FullyQualify(rec.rdata[field], p.origin)
+    }
+  }
+
+  //no explicit ttl: use $TTL if set, the SOA's minimum for the SOA itself or else the last ttl seen
+  if !hasTTL {
+    if p.hasDefaultTTL {
+      rec.ttl = p.defaultTTL
+    } else if rec.rrType == "SOA" && len(rec.rdata) == 7 {
+      ttl, err := ParseZoneFileTTL(rec.rdata[6])
+      if err != nil {
+        return err
+      }
+      rec.ttl = ttl
+    } else {
+      rec.ttl = p.lastTTL
+    }
+  }
+
+  if len(p.firstOrigin) == 0 && rec.rrType == "SOA" {
+    p.firstOrigin = rec.name
+  }
+
+  p.lastOwner = rec.name
+  p.lastTTL = rec.ttl
+  p.zf.records = append(p.zf.records, rec)
+
+  return nil
+}
+func (p *zoneFileParser) requiresOrigin(name string) bool {
+  return (len(p.origin) == 0 || p.origin == ".") && !strings.HasSuffix(name, ".")
+}
+
+/*
+ *  Splits master file content into logical entries. Handles comments, quoted
+ *  strings (kept with their quotes), escapes and parentheses that continue an
+ *  entry across lines.
+ */
+func TokenizeZoneFile(content string) ([]*zoneFileEntry, error) {
+  var current strings.Builder
+  var entries []*zoneFileEntry
+  var entry *zoneFileEntry
+  var depth int
+  var inQuotes bool
+  line := 1
+  atLineStart := true
+
+  flushToken := func() {
+    if current.Len() > 0 {
+      if entry == nil {
+        entry = &zoneFileEntry{line: line}
+      }
+      entry.tokens = append(entry.tokens, current.String())
+      current.Reset()
+    }
+  }
+  flushEntry := func() {
+    flushToken()
+    if entry != nil && len(entry.tokens) > 0 {
+      entries = append(entries, entry)
+    }
+    entry = nil
+  }
+
+  runes := []rune(content)
+  for i := 0; i < len(runes); i++ {
+    c := runes[i]
+
+    if inQuotes {
+      current.WriteRune(c)
+      if c == '\\' && i + 1 < len(runes) {
+        i++
+        current.WriteRune(runes[i])
+        if runes[i] == '\n' {
+          line++
+        }
+      } else if c == '"' {
+        inQuotes = false
+      } else if c == '\n' {
+        line++
+      }
+      continue
+    }
+
+    //a line starting with whitespace reuses the previous owner
+    if atLineStart && depth == 0 {
+      atLineStart = false
+      if c == ' ' || c == '\t' {
+        entry = &zoneFileEntry{line: line, ownerOmitted: true}
+      }
+    }
+
+    switch {
+    case c == '\\' && i + 1 < len(runes):
+      current.WriteRune(c)
+      i++
+      current.WriteRune(runes[i])
+    case c == '"':
+      current.WriteRune(c)
+      inQuotes = true
+    case c == ';':
+      for i + 1 < len(runes) && runes[i + 1] != '\n' {
+        i++
+      }
+    case c == '(':
+      flushToken()
+      depth++
+    case c == ')':
+      flushToken()
+      if depth == 0 {
+        return nil, fmt.Errorf("line %d: unbalanced parentheses", line)
+      }
+      depth--
+    case c == '\n':
+      flushToken()
+      if depth == 0 {
+        flushEntry()
+        atLineStart = true
+      }
+      line++
+    case c == ' ' || c == '\t' || c == '\r':
+      flushToken()
+    default:
+      if entry == nil {
+        entry = &zoneFileEntry{line: line}
+      }
+      current.WriteRune(c)
+    }
+  }
+
+  if inQuotes {
+    return nil, fmt.Errorf("line %d: unterminated quoted string", line)
+  }
+
+  if depth != 0 {
+    return nil, fmt.Errorf("line %d: unbalanced parentheses", line)
+  }
+
+  flushEntry()
+
+  return entries, nil
+}
+
+/*
+ *  hash master file records into a map of dns record types, one record per name
+ *  and type (mirrors how route53 returns recordsets)
+ */
+func (rset *recordset) HashZoneFileRecords(records []*zoneFileRecord) {
+  grouped := make(map[string]*record)
+
+  for _, zfRecord := range records {
+    key := zfRecord.rrType + " " + strings.ToLower(zfRecord.name)
+    currentRecordset, found := grouped[key]
+    if !found {
+      currentRecordset = new(record)
+      currentRecordset.rrType = zfRecord.rrType
+      currentRecordset.name = strings.TrimSuffix(zfRecord.name, ".")
+      currentRecordset.ttl = zfRecord.ttl
+      grouped[key] = currentRecordset
+      (*rset)[zfRecord.rrType] = append((*rset)[zfRecord.rrType], currentRecordset)
+    }
+
+    currentRecordset.values = append(currentRecordset.values, strings.Join(zfRecord.rdata, " "))
+  }
+}
@@ -0,0 +1,138 @@
+package main
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "testing"
+)
+
+/*
+ *  helper that writes zone files into a temporary directory
+ */
+func createZoneFiles(t *testing.T, files map[string]string) string {
+  dir, err := ioutil.TempDir("", "domania-zonefile")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  for name, content := range files {
+    if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  return dir
+}
+
+const testZoneFile string = `$ORIGIN example.com.
+$TTL 1h
+@   IN  SOA ns1 hostmaster (
+            2024010101 ; serial
+            7200       ; refresh
+            3600       ; retry
+            1209600    ; expire
+            300 )      ; minimum
+    IN  NS  ns1
+    IN  NS  ns2.example.net.
+    IN  MX  10 mail
+www 300 IN A 192.0.2.1
+        IN A 192.0.2.2
+txt     TXT "v=spf1 a mx; ~all" "second, string"
+$INCLUDE db.dev dev.example.com.
+api     CNAME www
+`
+const testIncludedZoneFile string = `app A 192.0.2.10
+`
+
+func TestParseZoneFile(t *testing.T) {
+  dir := createZoneFiles(t, map[string]string{"db.example": testZoneFile, "db.dev": testIncludedZoneFile})
+  defer os.RemoveAll(dir)
+
+  //test cases
+  //  1: origin is taken from $ORIGIN
+  //  2: parentheses continue the SOA across lines, comments are dropped
+  //  3: omitted owners reuse the previous owner; $TTL applies without an explicit ttl
+  //  4: relative rdata names are qualified with the origin
+  //  5: quoted strings stay whole (including ; and ,)
+  //  6: $INCLUDE with an origin applies only to the included file
+  zf, err := ParseZoneFile(filepath.Join(dir, "db.example"), "")
+  if err != nil {
+    t.Fatalf("unexpected error: %s", err.Error())
+  }
+
+  if zf.origin != "example.com." {
+    t.Errorf("tc1 - expected origin \"example.com.\", received: \"%s\"", zf.origin)
+  }
+
+  soa := zf.records[0]
+  if soa.rrType != "SOA" || len(soa.rdata) != 7 || soa.rdata[0] != "ns1.example.com." || soa.rdata[6] != "300" {
+    t.Errorf("tc2 - unexpected SOA: %+v", soa)
+  }
+
+  ns := zf.records[1]
+  if ns.name != "example.com." || ns.rrType != "NS" || ns.ttl != 3600 {
+    t.Errorf("tc3 - unexpected NS: %+v", ns)
+  }
+
+  mx := zf.records[3]
+  if mx.rdata[1] != "mail.example.com." {
+    t.Errorf("tc4 - expected qualified exchange, received: %+v", mx.rdata)
+  }
+
+  txt := zf.records[6]
+  if len(txt.rdata) != 2 || txt.rdata[0] != "\"v=spf1 a mx; ~all\"" {
+    t.Errorf("tc5 - unexpected TXT rdata: %+v", txt.rdata)
+  }
+
+  app, api := zf.records[7], zf.records[8]
+  if app.name != "app.dev.example.com." || api.name != "api.example.com." || api.rdata[0] != "www.example.com." {
+    t.Errorf("tc6 - unexpected names around $INCLUDE: %s, %s -> %s", app.name, api.name, api.rdata[0])
+  }
+}
+
+func TestParseZoneFileErrors(t *testing.T) {
+  dir := createZoneFiles(t, map[string]string{
+    "noorigin": "www A 192.0.2.1\n",
+    "unbalanced": "$ORIGIN example.com.\n@ SOA ns1 hostmaster ( 1 2 3 4 5\n",
+  })
+  defer os.RemoveAll(dir)
+
+  //test cases
+  //  1: relative names without an origin are rejected
+  //  2: an origin supplied by the caller makes relative names usable
+  //  3: unbalanced parentheses are rejected
+  if _, err := ParseZoneFile(filepath.Join(dir, "noorigin"), ""); err == nil {
+    t.Error("tc1 - expected an error for a relative name without an origin")
+  }
+
+  if zf, err := ParseZoneFile(filepath.Join(dir, "noorigin"), "example.org"); err != nil || zf.records[0].name != "www.example.org." {
+    t.Errorf("tc2 - expected www.example.org., received: %+v (error: %v)", zf, err)
+  }
+
+  if _, err := ParseZoneFile(filepath.Join(dir, "unbalanced"), ""); err == nil {
+    t.Error("tc3 - expected an error for unbalanced parentheses")
+  }
+}
+
+func TestBindProvider(t *testing.T) {
+  dir := createZoneFiles(t, map[string]string{"db.example": testZoneFile, "db.dev": testIncludedZoneFile})
+  defer os.RemoveAll(dir)
+
+  //test cases
+  //  1: each file becomes a zone named by its origin
+  //  2: records with the same name and type are grouped like route53 recordsets
+  provider, err := NewBindProvider([]string{filepath.Join(dir, "db.example")})
+  if err != nil {
+    t.Fatalf("unexpected error: %s", err.Error())
+  }
+
+  zones, _ := provider.GetZones()
+  if len(zones) != 1 || zones[0].id != "example.com" || zones[0].DomainToString() != "example.com" {
+    t.Errorf("tc1 - unexpected zones: %+v", zones)
+  }
+
+  zoneRecords, err := provider.GetRecordsets("example.com")
+  if err != nil || len((*zoneRecords)["A"]) != 2 || len((*zoneRecords)["A"][0].values) != 2 || (*zoneRecords)["A"][0].name != "www.example.com" {
+    t.Errorf("tc2 - unexpected A records: %+v (error: %v)", zoneRecords, err)
+  }
+}